@@ -0,0 +1,105 @@
+// Command beschema-infer generates Go structs with beschema tags from captured batchexecute responses.
+//
+// Usage:
+//
+//	beschema-infer [-name Entity] [-package main] [-rpcid id] [-o file] [file ...]
+//
+// Each file holds a captured response body, or a single JSON array. Without files the
+// standard input is read. With -rpcid, the payloads of that RPC's wrb.fr entries are
+// used as samples; otherwise every chunk of every body is a sample.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/starpia-forge/be-schema"
+)
+
+func main() {
+	name := flag.String("name", "Entity", "name of the root struct type")
+	pkg := flag.String("package", "main", "package clause of the generated file")
+	rpcID := flag.String("rpcid", "", "infer from the payloads of this RPC id instead of whole chunks")
+	output := flag.String("o", "", "write the generated source to this file instead of the standard output")
+	flag.Parse()
+
+	var samples []beschema.ImplicitSchema
+	if flag.NArg() == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		samples, err = readSamples(data, *rpcID)
+		if err != nil {
+			log.Fatalf("stdin: %v", err)
+		}
+	}
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fileSamples, err := readSamples(data, *rpcID)
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		samples = append(samples, fileSamples...)
+	}
+
+	src, err := beschema.InferStructs(*pkg, *name, samples...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readSamples parses a response body, or a single JSON array, into samples.
+func readSamples(data []byte, rpcID string) ([]beschema.ImplicitSchema, error) {
+	var schemas []beschema.ImplicitSchema
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		schema, err := beschema.UnmarshalImplicitSchema(bytes.TrimSpace(data), false)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	} else {
+		stream, err := beschema.UnmarshalImplicitStream(data)
+		if err != nil {
+			return nil, err
+		}
+		schemas = stream.Schemas
+	}
+
+	if rpcID == "" {
+		return schemas, nil
+	}
+
+	var samples []beschema.ImplicitSchema
+	for _, schema := range schemas {
+		results, err := beschema.SchemaResults(schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			if result.RPCID == rpcID && result.Payload != nil {
+				samples = append(samples, result.Payload)
+			}
+		}
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no payloads for RPC %s", rpcID)
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/starpia-forge/be-schema"
+)
+
+// errDiffer is returned by runDiff if the captures differ, so the command exits with status 1 like diff(1)
+var errDiffer = errors.New("captures differ")
+
+// runDiff prints the structural differences between two captures, one change per line.
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	matchRPCID := flags.Bool("rpcid", false, "match wrb.fr results by RPC id and index instead of chunks by position")
+	sizeMode := flags.String("size", "bytes", "unit of the size headers: bytes, runes, utf16, auto or lenient")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: beschema diff [-rpcid] [-size mode] old new")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	opts, err := decodeOptions(*sizeMode)
+	if err != nil {
+		return err
+	}
+
+	var captures [2]*capture
+	for i, name := range flags.Args() {
+		data, err := readInput(name)
+		if err != nil {
+			return err
+		}
+		captures[i], err = parseCapture(data, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	before, after := captures[0], captures[1]
+
+	var changes []beschema.Change
+	if !*matchRPCID && !before.stream && !after.stream {
+		// Two single arrays are compared directly, without a chunk number in the paths
+		changes = beschema.Diff(before.schemas[0], after.schemas[0])
+	} else {
+		var err error
+		changes, err = beschema.DiffStreams(
+			&beschema.Stream{Schemas: before.schemas},
+			&beschema.Stream{Schemas: after.schemas},
+			*matchRPCID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, change := range changes {
+		fmt.Fprintln(w, change)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		return errDiffer
+	}
+	return nil
+}
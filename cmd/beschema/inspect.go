@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/starpia-forge/be-schema"
+)
+
+// runInspect prints every value of a capture with its index path, e.g. [0][2][1] = "foo".
+func runInspect(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	unwrapJSON := flags.Bool("json", false, "descend into strings holding JSON arrays")
+	unwrapWRB := flags.Bool("wrb", false, "print wrb.fr envelopes with their RPC id and decoded payload")
+	sizeMode := flags.String("size", "bytes", "unit of the size headers: bytes, runes, utf16, auto or lenient")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: beschema inspect [-json] [-wrb] [-size mode] [file]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	data, err := readInput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	opts, err := decodeOptions(*sizeMode)
+	if err != nil {
+		return err
+	}
+
+	capture, err := parseCapture(data, opts)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	p := &printer{w: w, unwrapJSON: *unwrapJSON}
+	fmt.Fprintf(w, "# %s\n", capture.description)
+	for i, schema := range capture.schemas {
+		if capture.stream {
+			fmt.Fprintf(w, "# chunk %d\n", i)
+		}
+		if *unwrapWRB {
+			if err := p.printEntries(schema); err != nil {
+				return fmt.Errorf("chunk %d: %w", i, err)
+			}
+			continue
+		}
+		p.print("", []interface{}(schema))
+	}
+
+	return nil
+}
+
+// readInput reads the named file, or the standard input if name is empty or "-".
+func readInput(name string) ([]byte, error) {
+	if name == "" || name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// capture is a parsed input with the format it was detected as
+type capture struct {
+	description string
+	schemas     []beschema.ImplicitSchema
+	// stream is set if the input was a full stream, so chunks are numbered in the output
+	stream bool
+}
+
+// decodeOptions returns the options for a size mode given by name.
+func decodeOptions(sizeMode string) (beschema.DecodeOptions, error) {
+	for _, mode := range []beschema.SizeMode{beschema.SizeBytes, beschema.SizeRunes, beschema.SizeUTF16, beschema.SizeAuto, beschema.SizeLenient} {
+		if mode.String() == sizeMode {
+			return beschema.DecodeOptions{SizeMode: mode}, nil
+		}
+	}
+	return beschema.DecodeOptions{}, fmt.Errorf("unknown size mode %q", sizeMode)
+}
+
+// parseCapture detects whether data is a raw JSON array, a single size-prefixed chunk
+// or a full stream, and parses it. Size headers are validated by the parsers in opts.SizeMode.
+func parseCapture(data []byte, opts beschema.DecodeOptions) (*capture, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		schema, err := beschema.UnmarshalImplicitSchema(trimmed, false)
+		if err != nil {
+			return nil, err
+		}
+		return &capture{description: "raw JSON array", schemas: []beschema.ImplicitSchema{schema}}, nil
+	case bytes.HasPrefix(trimmed, []byte(beschema.DefaultMagicByte)):
+		var stream *beschema.Stream
+		status, err := checkSizes(opts, func(opts beschema.DecodeOptions) (err error) {
+			stream, err = beschema.UnmarshalImplicitStreamWithOptions(data, opts)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		description := fmt.Sprintf("stream with %d chunk(s), sizes %s", len(stream.Schemas), status)
+		return &capture{description: description, schemas: stream.Schemas, stream: true}, nil
+	default:
+		var schema beschema.ImplicitSchema
+		status, err := checkSizes(opts, func(opts beschema.DecodeOptions) (err error) {
+			schema, err = beschema.UnmarshalImplicitSchemaWithOptions(data, true, opts)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &capture{description: "size-prefixed chunk, size " + status, schemas: []beschema.ImplicitSchema{schema}}, nil
+	}
+}
+
+// checkSizes calls parse with opts and describes how the size headers were checked:
+// not at all in lenient mode, and in auto mode with the unit that matched every header.
+func checkSizes(opts beschema.DecodeOptions, parse func(beschema.DecodeOptions) error) (string, error) {
+	switch opts.SizeMode {
+	case beschema.SizeLenient:
+		return "not checked", parse(opts)
+	case beschema.SizeAuto:
+		for _, mode := range []beschema.SizeMode{beschema.SizeBytes, beschema.SizeRunes, beschema.SizeUTF16} {
+			unitOpts := opts
+			unitOpts.SizeMode = mode
+			if parse(unitOpts) == nil {
+				return "valid in " + mode.String(), nil
+			}
+		}
+		// Each header matched one of the units, but not all the same one
+		return "valid in mixed units", parse(opts)
+	default:
+		return "valid", parse(opts)
+	}
+}
+
+// printer writes values as one "path = value" line per leaf
+type printer struct {
+	w          io.Writer
+	unwrapJSON bool
+}
+
+// print writes value and, recursively, its elements. With unwrapJSON, strings holding
+// JSON arrays are descended into like by ImplicitSchema.Walk, so their paths are those accepted by Get.
+func (p *printer) print(path string, value interface{}) {
+	// Wrapping the value lets the walk visit scalars and the value itself too
+	beschema.ImplicitSchema{value}.Walk(func(elemPath string, value any) error {
+		elemPath = path + indexPath(strings.TrimPrefix(strings.TrimPrefix(elemPath, "0"), "."))
+		if arr, ok := value.([]any); ok {
+			if len(arr) == 0 {
+				fmt.Fprintf(p.w, "%s = []\n", elemPath)
+			}
+			return nil
+		}
+		p.printLeaf(elemPath, value)
+		return nil
+	}, p.unwrapJSON)
+}
+
+// indexPath converts a dot-separated path such as "2.1" to the form [2][1].
+func indexPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return "[" + strings.ReplaceAll(path, ".", "][") + "]"
+}
+
+// printLeaf writes a scalar value as JSON.
+func (p *printer) printLeaf(path string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", value))
+	}
+	fmt.Fprintf(p.w, "%s = %s\n", path, data)
+}
+
+// printEntries writes the entries of a chunk, with the payload of every wrb.fr envelope decoded.
+func (p *printer) printEntries(schema beschema.ImplicitSchema) error {
+	results, err := beschema.SchemaResults(schema)
+	if err != nil {
+		return err
+	}
+
+	// A chunk is either a list of entries or a single entry
+	entries := []interface{}(schema)
+	single := beschema.EntryTag(schema) != ""
+	if single {
+		entries = []interface{}{[]interface{}(schema)}
+	}
+
+	next := 0
+	for i, value := range entries {
+		path := ""
+		if !single {
+			path = fmt.Sprintf("[%d]", i)
+		}
+		entry, ok := value.([]interface{})
+		if !ok || beschema.EntryTag(entry) != beschema.TagResult {
+			p.print(path, value)
+			continue
+		}
+
+		result := results[next]
+		next++
+		if result.Index != "" {
+			fmt.Fprintf(p.w, "# %s %s (index %s)\n", beschema.TagResult, result.RPCID, result.Index)
+		} else {
+			fmt.Fprintf(p.w, "# %s %s\n", beschema.TagResult, result.RPCID)
+		}
+		for j, value := range entry {
+			// The payload slot holds the embedded JSON document decoded into the envelope
+			if j == 2 && result.Payload != nil {
+				p.print(fmt.Sprintf("%s[%d](json)", path, j), []interface{}(result.Payload))
+				continue
+			}
+			p.print(fmt.Sprintf("%s[%d]", path, j), value)
+		}
+	}
+
+	return nil
+}
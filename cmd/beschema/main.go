@@ -0,0 +1,54 @@
+// Command beschema inspects batchexecute captures.
+//
+// Usage:
+//
+//	beschema inspect [-json] [-wrb] [-size mode] [file]
+//	beschema diff [-rpcid] [-size mode] old new
+//
+// Without a file, or with "-" as a file name, the standard input is read.
+// diff exits with status 1 if the captures differ.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: beschema <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  inspect  print a raw JSON array, a size-prefixed chunk or a stream with index paths")
+	fmt.Fprintln(os.Stderr, "  diff     print the structural differences between two captures")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "inspect":
+		err = runInspect(os.Args[2:])
+	case "diff":
+		err = runDiff(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "beschema: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if errors.Is(err, errDiffer) {
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "beschema: %v\n", err)
+		os.Exit(1)
+	}
+}
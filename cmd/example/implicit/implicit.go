@@ -15,7 +15,7 @@ func main() {
 	}
 	log.Printf("Unmarshal: %+v\n", entity)
 
-	data, err = beschema.MarshalImplicitSchema(entity)
+	data, err = beschema.MarshalImplicitSchema(entity, true)
 	if err != nil {
 		log.Fatal(err)
 	}
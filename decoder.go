@@ -0,0 +1,194 @@
+package beschema
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Decoder reads size-prefixed chunks from an input stream one at a time.
+// Unlike UnmarshalImplicitStream, it never holds more than a single chunk in memory,
+// so a long-lived batchexecute response can be handled as chunks arrive.
+type Decoder struct {
+	r         *bufio.Reader
+	magicByte []byte
+	started   bool
+	chunk     int
+	// offset is the number of bytes consumed so far
+	offset int64
+	opts   DecodeOptions
+}
+
+// NewDecoder returns a new Decoder that reads from r.
+// The magic byte line (e.g. ")]}'") is stripped automatically when present.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// MagicByte returns the magic byte read from the head of the stream,
+// or nil if the stream does not start with one.
+func (d *Decoder) MagicByte() ([]byte, error) {
+	if err := d.readMagicByte(); err != nil {
+		return nil, err
+	}
+	return d.magicByte, nil
+}
+
+// SetDecodeOptions sets the options used to validate size headers and to populate structs,
+// e.g. DecodeOptions{Strict: true} to reject values that do not match their fields.
+func (d *Decoder) SetDecodeOptions(opts DecodeOptions) {
+	d.opts = opts
+}
+
+// More reports whether there is another chunk available in the stream.
+func (d *Decoder) More() bool {
+	if err := d.readMagicByte(); err != nil {
+		return false
+	}
+	if err := d.skipEmptyLines(); err != nil {
+		return false
+	}
+	_, err := d.r.Peek(1)
+	return err == nil
+}
+
+// Next reads the next chunk from the stream and returns it as an ImplicitSchema.
+// It returns io.EOF when there are no more chunks.
+func (d *Decoder) Next() (ImplicitSchema, error) {
+	var schema ImplicitSchema
+	if err := d.Decode(&schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// Decode reads the next chunk from the stream and stores it in the value pointed to by v.
+// If v is a *ImplicitSchema the chunk is decoded as is; otherwise v must be a pointer
+// to a struct with beschema tags, which is populated through the explicit schema path.
+// It returns io.EOF when there are no more chunks.
+func (d *Decoder) Decode(v any) error {
+	jsonData, offset, err := d.readChunk()
+	if err != nil {
+		return err
+	}
+	chunk := d.chunk - 1
+
+	if schema, ok := v.(*ImplicitSchema); ok {
+		if err := unmarshalJSON(jsonData, schema); err != nil {
+			return withLocation(newJSONSyntaxError("failed to unmarshal JSON", err, 0), chunk, offset)
+		}
+		return nil
+	}
+
+	var arr []interface{}
+	if err := unmarshalJSON(jsonData, &arr); err != nil {
+		return withLocation(newJSONSyntaxError("failed to unmarshal JSON", err, 0), chunk, offset)
+	}
+
+	if err := arrayToStruct(arr, v, d.opts); err != nil {
+		return withLocation(err, chunk, offset)
+	}
+
+	return nil
+}
+
+// readMagicByte consumes the magic byte line and the empty line following it.
+// It is a no-op after the first call.
+func (d *Decoder) readMagicByte() error {
+	if d.started {
+		return nil
+	}
+	d.started = true
+
+	prefix, err := d.r.Peek(len(DefaultMagicByte))
+	if err != nil || string(prefix) != DefaultMagicByte {
+		// No magic byte; the stream starts directly with a chunk
+		return nil
+	}
+
+	line, err := d.readLine()
+	if err != nil {
+		return err
+	}
+	d.magicByte = []byte(line)
+
+	return nil
+}
+
+// readChunk reads a single "size\r\nJSON_data\r\n" pair and returns the JSON data,
+// along with the byte offset in the stream at which the JSON data starts.
+// It validates the size information the same way UnmarshalImplicitSchema does.
+func (d *Decoder) readChunk() ([]byte, int64, error) {
+	if err := d.readMagicByte(); err != nil {
+		return nil, 0, err
+	}
+	if err := d.skipEmptyLines(); err != nil {
+		return nil, 0, err
+	}
+
+	// Parse size information from the first line
+	sizeOffset := d.offset
+	sizeLine, err := d.readLine()
+	if err != nil {
+		return nil, 0, err
+	}
+	expectedSize, err := strconv.Atoi(strings.TrimSpace(sizeLine))
+	if err != nil {
+		return nil, 0, &SyntaxError{Msg: "invalid size format", Chunk: d.chunk, Offset: sizeOffset, Err: err}
+	}
+
+	// Parse actual JSON data from the second line
+	dataOffset := d.offset
+	dataLine, err := d.readLine()
+	if err == io.EOF {
+		return nil, 0, &SyntaxError{Msg: "missing data", Chunk: d.chunk, Offset: dataOffset, Err: io.ErrUnexpectedEOF}
+	} else if err != nil {
+		return nil, 0, err
+	}
+	jsonData := strings.TrimSpace(dataLine)
+	dataOffset += int64(strings.Index(dataLine, jsonData))
+
+	// Actual data size is JSON data + \r\n
+	actualSize, ok := checkSize(expectedSize, []byte(jsonData), d.opts.SizeMode)
+	if !ok {
+		return nil, 0, &SizeMismatchError{Expected: expectedSize, Actual: actualSize, Chunk: d.chunk, Offset: sizeOffset}
+	}
+
+	d.chunk++
+	return []byte(jsonData), dataOffset, nil
+}
+
+// skipEmptyLines discards blank lines between chunks.
+func (d *Decoder) skipEmptyLines() error {
+	for {
+		b, err := d.r.Peek(1)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if b[0] != '\r' && b[0] != '\n' {
+			return nil
+		}
+		if _, err := d.r.ReadByte(); err != nil {
+			return err
+		}
+		d.offset++
+	}
+}
+
+// readLine reads a single line and strips its trailing line ending.
+// Both Windows (\r\n) and Unix (\n) line endings are supported.
+// It returns io.EOF only if no data is left at all.
+func (d *Decoder) readLine() (string, error) {
+	line, err := d.r.ReadString('\n')
+	d.offset += int64(len(line))
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
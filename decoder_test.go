@@ -0,0 +1,157 @@
+package beschema
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecoderNext(t *testing.T) {
+	// Test reading chunks one by one from a complete stream
+	streamData := ")]}'\r\n\r\n19\r\n[\"test1\",\"test2\"]\r\n14\r\n[\"data1\",42]\r\n"
+
+	decoder := NewDecoder(strings.NewReader(streamData))
+
+	magicByte, err := decoder.MagicByte()
+	if err != nil {
+		t.Fatalf("MagicByte failed: %v", err)
+	}
+	if string(magicByte) != DefaultMagicByte {
+		t.Errorf("Expected magic byte %q, got %q", DefaultMagicByte, string(magicByte))
+	}
+
+	schema1, err := decoder.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if len(schema1) != 2 || schema1[0] != "test1" || schema1[1] != "test2" {
+		t.Errorf("Expected first schema [test1 test2], got %v", schema1)
+	}
+
+	schema2, err := decoder.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if len(schema2) != 2 || schema2[0] != "data1" || schema2[1] != json.Number("42") {
+		t.Errorf("Expected second schema [data1 42], got %v", schema2)
+	}
+
+	if decoder.More() {
+		t.Errorf("Expected no more chunks")
+	}
+	if _, err := decoder.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecoderWithoutMagicByte(t *testing.T) {
+	// Test reading chunks from a stream that has no magic byte line
+	decoder := NewDecoder(strings.NewReader("19\n[\"test1\",\"test2\"]\n"))
+
+	schema, err := decoder.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if len(schema) != 2 || schema[0] != "test1" {
+		t.Errorf("Expected schema [test1 test2], got %v", schema)
+	}
+
+	magicByte, err := decoder.MagicByte()
+	if err != nil {
+		t.Fatalf("MagicByte failed: %v", err)
+	}
+	if magicByte != nil {
+		t.Errorf("Expected nil magic byte, got %q", string(magicByte))
+	}
+}
+
+func TestDecoderDecodeExplicit(t *testing.T) {
+	// Test decoding chunks into typed structs through the explicit schema path
+	streamData := ")]}'\r\n\r\n89\r\n[[\"test1\",\"test2\",\"[[null]]\",null,null,null,\"test3\"],[\"test4\",1],[\"test5\",2,\"test6\",3]]\r\n"
+
+	decoder := NewDecoder(strings.NewReader(streamData))
+
+	var entity EntityModified
+	if err := decoder.Decode(&entity); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if entity.Sub1.Field1 != "test1" {
+		t.Errorf("Expected Sub1.Field1 = 'test1', got '%s'", entity.Sub1.Field1)
+	}
+	if entity.Sub2.Field1 != "test6" {
+		t.Errorf("Expected Sub2.Field1 = 'test6', got '%s'", entity.Sub2.Field1)
+	}
+	if entity.Sub2.Field2 != "3" {
+		t.Errorf("Expected Sub2.Field2 = '3', got '%s'", entity.Sub2.Field2)
+	}
+}
+
+func TestDecoderReadsIncrementally(t *testing.T) {
+	// Test that the first chunk is available before the rest of the stream arrives
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	go func() {
+		pw.Write([]byte(")]}'\r\n\r\n19\r\n[\"test1\",\"test2\"]\r\n"))
+	}()
+
+	decoder := NewDecoder(pr)
+	schema, err := decoder.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if schema[0] != "test1" {
+		t.Errorf("Expected schema[0] = 'test1', got %v", schema[0])
+	}
+
+	go func() {
+		pw.Write([]byte("14\r\n[\"data1\",42]\r\n"))
+		pw.Close()
+	}()
+
+	schema, err = decoder.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if schema[0] != "data1" {
+		t.Errorf("Expected schema[0] = 'data1', got %v", schema[0])
+	}
+
+	if _, err := decoder.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestDecoderSizeMismatch(t *testing.T) {
+	// Test handling size mismatch in a chunk
+	decoder := NewDecoder(strings.NewReader(")]}'\r\n\r\n20\r\n[\"test\"]\r\n"))
+
+	_, err := decoder.Next()
+	if err == nil {
+		t.Fatalf("Expected error for size mismatch, got nil")
+	}
+
+	var sizeErr *SizeMismatchError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("Expected *SizeMismatchError, got %T: %v", err, err)
+	}
+	if sizeErr.Chunk != 0 || sizeErr.Offset != 8 || sizeErr.Expected != 20 || sizeErr.Actual != 10 {
+		t.Errorf("Expected size mismatch 20 != 10 at chunk 0 offset 8, got %+v", sizeErr)
+	}
+}
+
+func TestDecoderTruncatedChunk(t *testing.T) {
+	// Test handling a stream that ends after a size line
+	decoder := NewDecoder(strings.NewReader(")]}'\r\n\r\n20\r\n"))
+
+	_, err := decoder.Next()
+	if err == nil {
+		t.Fatalf("Expected error for truncated chunk, got nil")
+	}
+	if err == io.EOF {
+		t.Errorf("Expected truncated chunk error, got io.EOF")
+	}
+}
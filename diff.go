@@ -0,0 +1,203 @@
+package beschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind classifies a difference between two payloads.
+type ChangeKind int
+
+const (
+	// Added is a value that is null or missing in the old payload
+	Added ChangeKind = iota + 1
+	// Removed is a value that is null or missing in the new payload
+	Removed
+	// Changed is a value of the same JSON type with a different value
+	Changed
+	// TypeChanged is a value whose JSON type changed, e.g. from string to array
+	TypeChanged
+)
+
+// String returns the name of the change kind.
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	case TypeChanged:
+		return "type changed"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Change is a single difference between two payloads.
+type Change struct {
+	Kind ChangeKind
+	// Path is the dot-separated index path of the value, as accepted by ImplicitSchema.Get
+	Path string
+	// RPCID and Index identify the wrb.fr result the path is relative to,
+	// if the streams were matched by RPC id
+	RPCID string
+	Index string
+	// Old and New are the values in the old and new payload; nil if null or missing
+	Old any
+	New any
+}
+
+// String formats the change as a single line, e.g. `~ 0.2.1: "foo" -> "bar"`.
+func (c Change) String() string {
+	location := c.Path
+	if c.RPCID != "" {
+		location = strings.TrimSpace(c.RPCID + "/" + c.Index + " " + location)
+	}
+	if location == "" {
+		location = "(root)"
+	}
+
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", location, formatValue(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", location, formatValue(c.Old))
+	case TypeChanged:
+		return fmt.Sprintf("! %s: %s %s -> %s %s", location, jsonTypeName(c.Old), formatValue(c.Old), jsonTypeName(c.New), formatValue(c.New))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", location, formatValue(c.Old), formatValue(c.New))
+	}
+}
+
+// Diff compares two payloads structurally and returns their differences by index path.
+// A null slot and a missing trailing slot are treated as equivalent, and strings holding
+// JSON arrays are compared by their contents.
+func Diff(a, b ImplicitSchema) []Change {
+	return diffValues("", []any(a), []any(b), nil)
+}
+
+// DiffStreams compares two streams. Chunks are matched by position, with the chunk number
+// as the first path segment, unless matchRPCID is set, in which case the payloads of wrb.fr
+// results are matched by RPC id and index and other entries are ignored.
+func DiffStreams(a, b *Stream, matchRPCID bool) ([]Change, error) {
+	if !matchRPCID {
+		chunksA := make([]any, len(a.Schemas))
+		for i, schema := range a.Schemas {
+			chunksA[i] = []any(schema)
+		}
+		chunksB := make([]any, len(b.Schemas))
+		for i, schema := range b.Schemas {
+			chunksB[i] = []any(schema)
+		}
+		return diffValues("", chunksA, chunksB, nil), nil
+	}
+
+	resultsA, err := a.Results()
+	if err != nil {
+		return nil, err
+	}
+	resultsB, err := b.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Visit the results in a stable order
+	keys := make([]ResultKey, 0, len(resultsA)+len(resultsB))
+	for key := range resultsA {
+		keys = append(keys, key)
+	}
+	for key := range resultsB {
+		if _, ok := resultsA[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].RPCID != keys[j].RPCID {
+			return keys[i].RPCID < keys[j].RPCID
+		}
+		return keys[i].Index < keys[j].Index
+	})
+
+	var changes []Change
+	for _, key := range keys {
+		var payloadA, payloadB any
+		if result, ok := resultsA[key]; ok && result.Payload != nil {
+			payloadA = []any(result.Payload)
+		}
+		if result, ok := resultsB[key]; ok && result.Payload != nil {
+			payloadB = []any(result.Payload)
+		}
+
+		start := len(changes)
+		changes = diffValues("", payloadA, payloadB, changes)
+		for i := start; i < len(changes); i++ {
+			changes[i].RPCID, changes[i].Index = key.RPCID, key.Index
+		}
+	}
+
+	return changes, nil
+}
+
+// diffValues appends the differences between two values at path to changes.
+func diffValues(path string, a, b any, changes []Change) []Change {
+	switch {
+	case a == nil && b == nil:
+		return changes
+	case a == nil:
+		return append(changes, Change{Kind: Added, Path: path, New: b})
+	case b == nil:
+		return append(changes, Change{Kind: Removed, Path: path, Old: a})
+	}
+
+	arrA, okA := a.([]any)
+	arrB, okB := b.([]any)
+
+	// Embedded JSON documents are compared by their contents
+	if !okA && !okB {
+		strA, isStrA := a.(string)
+		strB, isStrB := b.(string)
+		if isStrA && isStrB {
+			parsedA, isJSONA := embeddedArray(strA)
+			parsedB, isJSONB := embeddedArray(strB)
+			if isJSONA && isJSONB {
+				arrA, arrB, okA, okB = parsedA, parsedB, true, true
+			}
+		}
+	}
+
+	switch {
+	case okA && okB:
+		length := max(len(arrA), len(arrB))
+		for i := 0; i < length; i++ {
+			var elemA, elemB any
+			if i < len(arrA) {
+				elemA = arrA[i]
+			}
+			if i < len(arrB) {
+				elemB = arrB[i]
+			}
+			changes = diffValues(joinPath(path, i), elemA, elemB, changes)
+		}
+		return changes
+	case jsonTypeName(a) != jsonTypeName(b):
+		return append(changes, Change{Kind: TypeChanged, Path: path, Old: a, New: b})
+	case !reflect.DeepEqual(a, b):
+		return append(changes, Change{Kind: Changed, Path: path, Old: a, New: b})
+	default:
+		return changes
+	}
+}
+
+// formatValue formats a value as JSON for display.
+func formatValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
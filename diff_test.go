@@ -0,0 +1,106 @@
+package beschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	// Test that added, removed, changed and retyped values are reported by index path
+	a := ImplicitSchema{"test1", []interface{}{"test2", float64(1), nil}, "removed", float64(3), `[["a"],1]`}
+	b := ImplicitSchema{"test1", []interface{}{"test3", float64(1), "added"}, nil, []interface{}{float64(3)}, `[["b"],1]`, "tail"}
+
+	changes := Diff(a, b)
+
+	expected := []Change{
+		{Kind: Changed, Path: "1.0", Old: "test2", New: "test3"},
+		{Kind: Added, Path: "1.2", New: "added"},
+		{Kind: Removed, Path: "2", Old: "removed"},
+		{Kind: TypeChanged, Path: "3", Old: float64(3), New: []interface{}{float64(3)}},
+		{Kind: Changed, Path: "4.0.0", Old: "a", New: "b"},
+		{Kind: Added, Path: "5", New: "tail"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+}
+
+func TestDiffTrailingNulls(t *testing.T) {
+	// Test that null and missing trailing slots are equivalent
+	a := ImplicitSchema{"test1", []interface{}{"test2", nil}, nil, nil}
+	b := ImplicitSchema{"test1", []interface{}{"test2"}}
+
+	if changes := Diff(a, b); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+	if changes := Diff(b, a); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	// Test the single line format of each change kind
+	testCases := []struct {
+		change   Change
+		expected string
+	}{
+		{Change{Kind: Added, Path: "0.1", New: "foo"}, `+ 0.1: "foo"`},
+		{Change{Kind: Removed, Path: "0.1", Old: float64(2)}, `- 0.1: 2`},
+		{Change{Kind: Changed, Path: "0.1", Old: "foo", New: "bar"}, `~ 0.1: "foo" -> "bar"`},
+		{Change{Kind: TypeChanged, Path: "0.1", Old: "1", New: float64(1)}, `! 0.1: string "1" -> number 1`},
+		{Change{Kind: Added, RPCID: "abc123", Index: "generic", New: []interface{}{}}, `+ abc123/generic: []`},
+		{Change{Kind: Removed, Old: []interface{}{}}, `- (root): []`},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.change.String(); got != tc.expected {
+			t.Errorf("Expected %s, got %s", tc.expected, got)
+		}
+	}
+}
+
+func TestDiffStreams(t *testing.T) {
+	// Test that chunks are matched by position, with the chunk number first in the path
+	a := &Stream{Schemas: []ImplicitSchema{{"test1"}, {"test2"}}}
+	b := &Stream{Schemas: []ImplicitSchema{{"test1"}, {"test3"}, {"test4"}}}
+
+	changes, err := DiffStreams(a, b, false)
+	if err != nil {
+		t.Fatalf("DiffStreams failed: %v", err)
+	}
+
+	expected := []Change{
+		{Kind: Changed, Path: "1.0", Old: "test2", New: "test3"},
+		{Kind: Added, Path: "2", New: []interface{}{"test4"}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+}
+
+func TestDiffStreamsByRPCID(t *testing.T) {
+	// Test that results are matched by RPC id regardless of chunk order and metadata entries
+	a := &Stream{Schemas: []ImplicitSchema{
+		{[]interface{}{"wrb.fr", "abc123", `["test1","test2"]`, nil, nil, nil, "generic"}},
+		{[]interface{}{"wrb.fr", "def456", `["test5"]`, nil, nil, nil, "generic"}, []interface{}{"di", float64(10)}},
+	}}
+	b := &Stream{Schemas: []ImplicitSchema{
+		{[]interface{}{"wrb.fr", "ghi789", `[1]`, nil, nil, nil, "generic"}, []interface{}{"di", float64(22)}},
+		{[]interface{}{"wrb.fr", "abc123", `["test1","test3"]`, nil, nil, nil, "generic"}},
+	}}
+
+	changes, err := DiffStreams(a, b, true)
+	if err != nil {
+		t.Fatalf("DiffStreams failed: %v", err)
+	}
+
+	expected := []Change{
+		{Kind: Changed, Path: "1", RPCID: "abc123", Index: "generic", Old: "test2", New: "test3"},
+		{Kind: Removed, Path: "", RPCID: "def456", Index: "generic", Old: []interface{}{"test5"}},
+		{Kind: Added, Path: "", RPCID: "ghi789", Index: "generic", New: []interface{}{json.Number("1")}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected %v, got %v", expected, changes)
+	}
+}
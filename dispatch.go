@@ -0,0 +1,135 @@
+package beschema
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnregisteredRPC is reported for results whose RPC id has no registered type.
+var ErrUnregisteredRPC = errors.New("unregistered RPC id")
+
+// RPCError is a per-RPC error reported by the server, either as a "wrb.fr" entry
+// with an error status or as an "er" entry.
+type RPCError struct {
+	RPCID string
+	Index string
+	// Entry is the raw entry the error was read from
+	Entry ImplicitSchema
+}
+
+// Error implements the error interface.
+func (e *RPCError) Error() string {
+	if e.RPCID == "" {
+		return fmt.Sprintf("RPC error: %v", []interface{}(e.Entry))
+	}
+	return fmt.Sprintf("RPC %s (index %s) failed: %v", e.RPCID, e.Index, []interface{}(e.Entry))
+}
+
+// TypedResult is the typed result of a single RPC call.
+type TypedResult struct {
+	RPCID string
+	Index string
+	// Value holds a value of the type registered for RPCID; it is nil if Err is set
+	Value any
+	// Err holds the per-RPC error, such as an *RPCError or ErrUnregisteredRPC
+	Err error
+}
+
+// Registry maps RPC ids to the Go types their payloads are decoded into.
+type Registry struct {
+	decoders map[string]func(payload ImplicitSchema) (any, error)
+	opts     DecodeOptions
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{decoders: make(map[string]func(payload ImplicitSchema) (any, error))}
+}
+
+// SetDecodeOptions sets the options used to decode payloads into their registered types.
+func (r *Registry) SetDecodeOptions(opts DecodeOptions) {
+	r.opts = opts
+}
+
+// Register registers T as the type that payloads of rpcID are decoded into.
+// T must be a struct with beschema tags, as for UnmarshalExplicitSchema.
+func Register[T any](r *Registry, rpcID string) {
+	r.decoders[rpcID] = func(payload ImplicitSchema) (any, error) {
+		var result T
+		if err := arrayToStruct(payload, &result, r.opts); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+}
+
+// Dispatch decodes every result entry of the stream into its registered type,
+// in order of appearance.
+func (r *Registry) Dispatch(stream *Stream) ([]TypedResult, error) {
+	var results []TypedResult
+	for i, schema := range stream.Schemas {
+		schemaResults, err := r.DispatchSchema(schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dispatch chunk %d: %w", i, err)
+		}
+		results = append(results, schemaResults...)
+	}
+
+	return results, nil
+}
+
+// Next reads the next chunk from a live decoder and dispatches its result entries.
+// It returns io.EOF when there are no more chunks.
+func (r *Registry) Next(d *Decoder) ([]TypedResult, error) {
+	schema, err := d.Next()
+	if err != nil {
+		return nil, err
+	}
+	return r.DispatchSchema(schema)
+}
+
+// DispatchSchema decodes the result entries of a single chunk into their registered types.
+// Per-RPC failures are reported in TypedResult.Err; the returned error is only set
+// if an entry cannot be read as an envelope at all.
+func (r *Registry) DispatchSchema(schema ImplicitSchema) ([]TypedResult, error) {
+	var results []TypedResult
+	for i, entry := range SchemaEntries(schema) {
+		switch EntryTag(entry) {
+		case TagResult:
+			var envelope ResultEnvelope
+			if err := arrayToStruct(entry, &envelope, DecodeOptions{}); err != nil {
+				return nil, fmt.Errorf("failed to parse entry %d: %w", i, err)
+			}
+			results = append(results, r.dispatchResult(envelope, entry))
+		case TagError:
+			results = append(results, TypedResult{Err: &RPCError{Entry: entry}})
+		}
+	}
+
+	return results, nil
+}
+
+// dispatchResult decodes the payload of a single result envelope into its registered type.
+func (r *Registry) dispatchResult(envelope ResultEnvelope, entry []interface{}) TypedResult {
+	result := TypedResult{RPCID: envelope.RPCID, Index: envelope.Index}
+
+	if envelope.Status != nil {
+		result.Err = &RPCError{RPCID: envelope.RPCID, Index: envelope.Index, Entry: entry}
+		return result
+	}
+
+	decode, ok := r.decoders[envelope.RPCID]
+	if !ok {
+		result.Err = fmt.Errorf("%w: %s", ErrUnregisteredRPC, envelope.RPCID)
+		return result
+	}
+
+	value, err := decode(envelope.Payload)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to decode payload of %s: %w", envelope.RPCID, err)
+		return result
+	}
+	result.Value = value
+
+	return result
+}
@@ -0,0 +1,156 @@
+package beschema
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// newDispatchStream returns a stream mixing the results of two RPCs, a failed call and metadata
+func newDispatchStream() *Stream {
+	return &Stream{
+		MagicByte: []byte(DefaultMagicByte),
+		Schemas: []ImplicitSchema{
+			{[]interface{}{"wrb.fr", "abc123", `["test1","test2"]`, nil, nil, nil, "1"}},
+			{[]interface{}{"wrb.fr", "def456", `["test5",2,"test6",3]`, nil, nil, nil, "2"}},
+			{[]interface{}{"wrb.fr", "ghi789", nil, nil, nil, []interface{}{float64(3)}, "3"}},
+			{[]interface{}{"er", nil, nil, nil, nil, float64(400), nil, nil, nil, float64(3)}, []interface{}{"di", float64(22)}},
+		},
+	}
+}
+
+func newDispatchRegistry() *Registry {
+	registry := NewRegistry()
+	Register[SubEntity1](registry, "abc123")
+	Register[SubEntity2Modified](registry, "def456")
+	Register[SubEntity1](registry, "ghi789")
+	return registry
+}
+
+func TestRegistryDispatch(t *testing.T) {
+	// Test that results are decoded into the type registered for their RPC id
+	results, err := newDispatchRegistry().Dispatch(newDispatchStream())
+	if err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+
+	first, ok := results[0].Value.(SubEntity1)
+	if results[0].Err != nil || !ok {
+		t.Fatalf("Expected SubEntity1 result, got %+v", results[0])
+	}
+	if results[0].RPCID != "abc123" || results[0].Index != "1" {
+		t.Errorf("Expected abc123/1, got %s/%s", results[0].RPCID, results[0].Index)
+	}
+	if first.Field1 != "test1" || first.Field2 != "test2" {
+		t.Errorf("Expected {test1 test2}, got %+v", first)
+	}
+
+	second, ok := results[1].Value.(SubEntity2Modified)
+	if results[1].Err != nil || !ok {
+		t.Fatalf("Expected SubEntity2Modified result, got %+v", results[1])
+	}
+	if second.Field1 != "test6" || second.Field2 != "3" {
+		t.Errorf("Expected {test6 3}, got %+v", second)
+	}
+}
+
+func TestRegistryDispatchErrors(t *testing.T) {
+	// Test that failed calls and er entries are surfaced as per-RPC errors
+	results, err := newDispatchRegistry().Dispatch(newDispatchStream())
+	if err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+
+	var rpcErr *RPCError
+	if !errors.As(results[2].Err, &rpcErr) {
+		t.Fatalf("Expected *RPCError for failed call, got %v", results[2].Err)
+	}
+	if rpcErr.RPCID != "ghi789" || rpcErr.Index != "3" {
+		t.Errorf("Expected error for ghi789/3, got %s/%s", rpcErr.RPCID, rpcErr.Index)
+	}
+	if results[2].Value != nil {
+		t.Errorf("Expected nil value for failed call, got %v", results[2].Value)
+	}
+
+	if !errors.As(results[3].Err, &rpcErr) {
+		t.Fatalf("Expected *RPCError for er entry, got %v", results[3].Err)
+	}
+	if rpcErr.RPCID != "" || EntryTag(rpcErr.Entry) != TagError {
+		t.Errorf("Expected er entry error, got %+v", rpcErr)
+	}
+}
+
+func TestRegistryDispatchUnregistered(t *testing.T) {
+	// Test that results without a registered type are reported as ErrUnregisteredRPC
+	registry := NewRegistry()
+	Register[SubEntity1](registry, "abc123")
+
+	results, err := registry.DispatchSchema(ImplicitSchema{
+		[]interface{}{"wrb.fr", "unknown", `[]`, nil, nil, nil, "generic"},
+	})
+	if err != nil {
+		t.Fatalf("DispatchSchema failed: %v", err)
+	}
+
+	if len(results) != 1 || !errors.Is(results[0].Err, ErrUnregisteredRPC) {
+		t.Errorf("Expected ErrUnregisteredRPC, got %+v", results)
+	}
+}
+
+func TestRegistryNext(t *testing.T) {
+	// Test dispatching chunk by chunk from a live decoder
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+	for _, schema := range newDispatchStream().Schemas {
+		if err := encoder.Encode(schema); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+
+	registry := newDispatchRegistry()
+	decoder := NewDecoder(&buf)
+
+	var results []TypedResult
+	for {
+		chunkResults, err := registry.Next(decoder)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		results = append(results, chunkResults...)
+	}
+
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	if value, ok := results[0].Value.(SubEntity1); !ok || value.Field1 != "test1" {
+		t.Errorf("Expected SubEntity1 {test1 test2}, got %+v", results[0])
+	}
+}
+
+func TestRegistryDispatchStrict(t *testing.T) {
+	// Test that registries apply their decode options to every payload
+	registry := newDispatchRegistry()
+	registry.SetDecodeOptions(DecodeOptions{Strict: true})
+
+	results, err := registry.DispatchSchema(ImplicitSchema{
+		[]interface{}{"wrb.fr", "def456", `["test5",2,"test6",3]`, nil, nil, nil, "1"},
+	})
+	if err != nil {
+		t.Fatalf("DispatchSchema failed: %v", err)
+	}
+
+	var typeErr *TypeMismatchError
+	if len(results) != 1 || !errors.As(results[0].Err, &typeErr) {
+		t.Fatalf("Expected a *TypeMismatchError result, got %+v", results)
+	}
+	if typeErr.Field != "SubEntity2Modified.Field2" {
+		t.Errorf("Expected error at SubEntity2Modified.Field2, got %s", typeErr.Field)
+	}
+}
@@ -0,0 +1,126 @@
+package beschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Encoder writes size-prefixed chunks to an output stream one at a time.
+// The magic byte line is written once, before the first chunk.
+type Encoder struct {
+	w           io.Writer
+	magicByte   []byte
+	wroteHeader bool
+	autoFlush   bool
+	opts        EncodeOptions
+}
+
+// NewEncoder returns a new Encoder that writes to w using DefaultMagicByte.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:         w,
+		magicByte: []byte(DefaultMagicByte),
+	}
+}
+
+// SetMagicByte overrides the magic byte written at the head of the stream.
+// A nil magic byte disables the header, so only chunks are written.
+// It has no effect once the header has been written.
+func (e *Encoder) SetMagicByte(magicByte []byte) {
+	e.magicByte = magicByte
+}
+
+// SetAutoFlush makes the Encoder flush the underlying writer after every chunk,
+// if it supports flushing (e.g. http.Flusher or *bufio.Writer).
+func (e *Encoder) SetAutoFlush(autoFlush bool) {
+	e.autoFlush = autoFlush
+}
+
+// SetEncodeOptions sets the options used to write chunks,
+// e.g. EncodeOptions{SizeMode: SizeUTF16} to count size headers in UTF-16 code units.
+func (e *Encoder) SetEncodeOptions(opts EncodeOptions) {
+	e.opts = opts
+}
+
+// Encode writes v to the stream as a single "size\r\nJSON_data\r\n" chunk.
+// v may be an ImplicitSchema or a struct (or pointer to struct) with beschema tags.
+func (e *Encoder) Encode(v any) error {
+	var arr any
+	switch value := v.(type) {
+	case ImplicitSchema:
+		arr = e.implicitArray(value)
+	case *ImplicitSchema:
+		arr = e.implicitArray(*value)
+	default:
+		// Struct output is already trimmed at every level by structToArray
+		structArr, err := structToArray(v, e.opts)
+		if err != nil {
+			return err
+		}
+		arr = structArr
+	}
+
+	jsonData, err := json.Marshal(arr)
+	if err != nil {
+		return fmt.Errorf("failed to marshal to JSON: %w", err)
+	}
+
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	// Combine with size information (JSON data + \r\n)
+	size := chunkSize(jsonData, e.opts.SizeMode)
+	if _, err := fmt.Fprintf(e.w, "%d\r\n%s\r\n", size, jsonData); err != nil {
+		return err
+	}
+
+	if e.autoFlush {
+		return e.flushWriter()
+	}
+	return nil
+}
+
+// implicitArray returns the array to write for an ImplicitSchema, without trailing nulls if the options say so.
+func (e *Encoder) implicitArray(schema ImplicitSchema) any {
+	if e.opts.TrimTrailingNulls {
+		return trimTrailingNulls(schema)
+	}
+	return schema
+}
+
+// Flush writes the magic byte header if it has not been written yet
+// and flushes the underlying writer if it supports flushing.
+func (e *Encoder) Flush() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.flushWriter()
+}
+
+// writeHeader writes the magic byte followed by an empty line.
+// It is a no-op after the first call.
+func (e *Encoder) writeHeader() error {
+	if e.wroteHeader {
+		return nil
+	}
+	e.wroteHeader = true
+
+	if e.magicByte == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(e.w, "%s\r\n\r\n", e.magicByte)
+	return err
+}
+
+// flushWriter flushes the underlying writer if it implements a Flush method.
+func (e *Encoder) flushWriter() error {
+	switch f := e.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
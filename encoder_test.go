@@ -0,0 +1,155 @@
+package beschema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// flushRecorder is an io.Writer that counts calls to Flush, like http.Flusher
+type flushRecorder struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes++
+}
+
+func TestEncoderEncodeImplicitSchemas(t *testing.T) {
+	// Test that the encoder produces the same bytes as MarshalImplicitStream
+	stream := &Stream{
+		MagicByte: []byte(DefaultMagicByte),
+		Schemas: []ImplicitSchema{
+			{"test1", "test2"},
+			{"data1", 42},
+		},
+	}
+
+	expected, err := MarshalImplicitStream(stream)
+	if err != nil {
+		t.Fatalf("MarshalImplicitStream failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+	for _, schema := range stream.Schemas {
+		if err := encoder.Encode(schema); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+
+	if buf.String() != string(expected) {
+		t.Errorf("Expected %q, got %q", string(expected), buf.String())
+	}
+}
+
+func TestEncoderEncodeExplicitStruct(t *testing.T) {
+	// Test that tagged structs are written as chunks through the explicit schema path
+	entity := Entity{
+		Sub1: SubEntity1{Field1: "test1", Field2: "test2"},
+		Sub2: SubEntity2{Field1: "test6", Field2: "3"},
+	}
+
+	chunk, err := MarshalExplicitSchema(entity)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+	if err := encoder.Encode(&entity); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := DefaultMagicByte + "\r\n\r\n" + string(chunk)
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	// Verify the output can be read back by the decoder
+	decoder := NewDecoder(&buf)
+	var result Entity
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if result != entity {
+		t.Errorf("Expected %+v, got %+v", entity, result)
+	}
+}
+
+func TestEncoderWithoutMagicByte(t *testing.T) {
+	// Test writing chunks only, without the magic byte header
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+	encoder.SetMagicByte(nil)
+
+	if err := encoder.Encode(ImplicitSchema{"test1", "test2"}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "19\r\n[\"test1\",\"test2\"]\r\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestEncoderFlush(t *testing.T) {
+	// Test that Flush writes the header of an empty stream and auto flush flushes every chunk
+	recorder := &flushRecorder{}
+	encoder := NewEncoder(recorder)
+
+	if err := encoder.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if recorder.String() != DefaultMagicByte+"\r\n\r\n" {
+		t.Errorf("Expected only the magic byte header, got %q", recorder.String())
+	}
+	if recorder.flushes != 1 {
+		t.Errorf("Expected 1 flush, got %d", recorder.flushes)
+	}
+
+	encoder.SetAutoFlush(true)
+	for i := 0; i < 2; i++ {
+		if err := encoder.Encode(ImplicitSchema{"test"}); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if recorder.flushes != 3 {
+		t.Errorf("Expected 3 flushes, got %d", recorder.flushes)
+	}
+	if strings.Count(recorder.String(), DefaultMagicByte) != 1 {
+		t.Errorf("Expected the magic byte to be written once, got %q", recorder.String())
+	}
+}
+
+func TestEncoderTrimTrailingNulls(t *testing.T) {
+	// Test that trailing nulls are trimmed from implicit schemas and structs without modifying the input
+	schema := ImplicitSchema{"test1", []interface{}{nil, nil}, nil}
+	entity := SubEntity2{Field1: "test5"}
+
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+	encoder.SetMagicByte(nil)
+	encoder.SetEncodeOptions(EncodeOptions{TrimTrailingNulls: true})
+	if err := encoder.Encode(schema); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := encoder.Encode(&SearchRequest{Query: "test2"}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := encoder.Encode(entity); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "14\r\n[\"test1\",[]]\r\n" +
+		"41\r\n[\"test2\",null,null,null,null,null,[\"\"]]\r\n" +
+		"14\r\n[\"test5\",\"\"]\r\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+
+	if len(schema) != 3 || len(schema[1].([]interface{})) != 2 {
+		t.Errorf("Expected the schema to be left unchanged, got %v", schema)
+	}
+}
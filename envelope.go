@@ -0,0 +1,111 @@
+package beschema
+
+import "fmt"
+
+// Envelope tags found at index 0 of every entry in a batchexecute response chunk.
+const (
+	// TagResult marks an RPC result entry: ["wrb.fr", rpcId, payloadJSON, null, null, null, "generic"]
+	TagResult = "wrb.fr"
+	// TagDI marks a server timing entry: ["di", ms]
+	TagDI = "di"
+	// TagHTTPRM marks a response metadata entry: ["af.httprm", ms, "...", n]
+	TagHTTPRM = "af.httprm"
+	// TagError marks an error entry that is not tied to a single result: ["er", ...]
+	TagError = "er"
+)
+
+// DefaultIndex is the index used for a single RPC call that was not given an explicit index.
+const DefaultIndex = "generic"
+
+// ResultEnvelope is a "wrb.fr" entry carrying the result of a single RPC call.
+// The payload is a JSON document encoded as a string, decoded here as an ImplicitSchema.
+// A failed call has a null payload and its error status in Status, e.g. [3].
+type ResultEnvelope struct {
+	Tag     string         `beschema:"1"`
+	RPCID   string         `beschema:"2"`
+	Payload ImplicitSchema `beschema:"3,json"`
+	Status  ImplicitSchema `beschema:"6"`
+	Index   string         `beschema:"7"`
+}
+
+// DIEnvelope is a "di" entry carrying the server processing time in milliseconds.
+type DIEnvelope struct {
+	Tag     string `beschema:"1"`
+	Elapsed int64  `beschema:"2"`
+}
+
+// HTTPRMEnvelope is an "af.httprm" entry carrying response metadata.
+type HTTPRMEnvelope struct {
+	Tag     string `beschema:"1"`
+	Elapsed int64  `beschema:"2"`
+	Value   string `beschema:"3"`
+	Code    int64  `beschema:"4"`
+}
+
+// ResultKey identifies a single RPC call within a batched request by its RPC id and index.
+type ResultKey struct {
+	RPCID string
+	Index string
+}
+
+// Results returns all "wrb.fr" entries of the stream keyed by RPC id and index,
+// with their payloads already decoded.
+func (s *Stream) Results() (map[ResultKey]ResultEnvelope, error) {
+	results := make(map[ResultKey]ResultEnvelope)
+	for i, schema := range s.Schemas {
+		envelopes, err := SchemaResults(schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse results at chunk %d: %w", i, err)
+		}
+		for _, envelope := range envelopes {
+			results[ResultKey{RPCID: envelope.RPCID, Index: envelope.Index}] = envelope
+		}
+	}
+
+	return results, nil
+}
+
+// SchemaResults returns the "wrb.fr" entries of a single chunk in order of appearance.
+func SchemaResults(schema ImplicitSchema) ([]ResultEnvelope, error) {
+	var results []ResultEnvelope
+	for i, entry := range SchemaEntries(schema) {
+		if EntryTag(entry) != TagResult {
+			continue
+		}
+
+		var envelope ResultEnvelope
+		if err := arrayToStruct(entry, &envelope, DecodeOptions{}); err != nil {
+			return nil, fmt.Errorf("failed to parse entry %d: %w", i, err)
+		}
+		results = append(results, envelope)
+	}
+
+	return results, nil
+}
+
+// SchemaEntries returns the envelope entries of a chunk.
+// A chunk normally holds a list of entries, e.g. [["wrb.fr",...],["di",12]],
+// but a chunk that is itself a single entry, e.g. ["di",12], is accepted as well.
+func SchemaEntries(schema ImplicitSchema) [][]interface{} {
+	if EntryTag(schema) != "" {
+		return [][]interface{}{schema}
+	}
+
+	var entries [][]interface{}
+	for _, value := range schema {
+		if entry, ok := value.([]interface{}); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// EntryTag returns the envelope tag at index 0 of an entry, or an empty string if there is none.
+func EntryTag(entry []interface{}) string {
+	if len(entry) == 0 {
+		return ""
+	}
+	tag, _ := entry[0].(string)
+	return tag
+}
@@ -0,0 +1,112 @@
+package beschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// sampleEnvelopeStream is a batchexecute response with two RPC results and trailing metadata
+const sampleEnvelopeStream = ")]}'\r\n\r\n" +
+	"60\r\n[[\"wrb.fr\",\"abc123\",\"[[\\\"test1\\\",1]]\",null,null,null,\"1\"]]\r\n" +
+	"64\r\n[[\"wrb.fr\",\"def456\",\"[\\\"test2\\\",2]\",null,null,null,\"generic\"]]\r\n" +
+	"50\r\n[[\"di\",51],[\"af.httprm\",50,\"-1234567890123\",25]]\r\n"
+
+func TestStreamResults(t *testing.T) {
+	// Test collecting wrb.fr results keyed by RPC id and index
+	stream, err := UnmarshalImplicitStream([]byte(sampleEnvelopeStream))
+	if err != nil {
+		t.Fatalf("UnmarshalImplicitStream failed: %v", err)
+	}
+
+	results, err := stream.Results()
+	if err != nil {
+		t.Fatalf("Results failed: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	first, ok := results[ResultKey{RPCID: "abc123", Index: "1"}]
+	if !ok {
+		t.Fatalf("Expected result for abc123/1, got %v", results)
+	}
+	if first.Tag != TagResult {
+		t.Errorf("Expected tag %q, got %q", TagResult, first.Tag)
+	}
+	inner, ok := first.Payload[0].([]interface{})
+	if !ok || inner[0] != "test1" || inner[1] != json.Number("1") {
+		t.Errorf("Expected payload [[test1 1]], got %v", first.Payload)
+	}
+
+	second, ok := results[ResultKey{RPCID: "def456", Index: DefaultIndex}]
+	if !ok {
+		t.Fatalf("Expected result for def456/generic, got %v", results)
+	}
+	if len(second.Payload) != 2 || second.Payload[0] != "test2" {
+		t.Errorf("Expected payload [test2 2], got %v", second.Payload)
+	}
+}
+
+func TestEnvelopeEntries(t *testing.T) {
+	// Test decoding the metadata entries into their typed envelopes
+	stream, err := UnmarshalImplicitStream([]byte(sampleEnvelopeStream))
+	if err != nil {
+		t.Fatalf("UnmarshalImplicitStream failed: %v", err)
+	}
+
+	entries := SchemaEntries(stream.Schemas[2])
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	if EntryTag(entries[0]) != TagDI {
+		t.Errorf("Expected tag %q, got %q", TagDI, EntryTag(entries[0]))
+	}
+	var di DIEnvelope
+	if err := arrayToStruct(entries[0], &di, DecodeOptions{}); err != nil {
+		t.Fatalf("arrayToStruct failed: %v", err)
+	}
+	if di.Elapsed != 51 {
+		t.Errorf("Expected Elapsed = 51, got %d", di.Elapsed)
+	}
+
+	if EntryTag(entries[1]) != TagHTTPRM {
+		t.Errorf("Expected tag %q, got %q", TagHTTPRM, EntryTag(entries[1]))
+	}
+	var httprm HTTPRMEnvelope
+	if err := arrayToStruct(entries[1], &httprm, DecodeOptions{}); err != nil {
+		t.Fatalf("arrayToStruct failed: %v", err)
+	}
+	if httprm.Elapsed != 50 || httprm.Value != "-1234567890123" || httprm.Code != 25 {
+		t.Errorf("Expected {af.httprm 50 -1234567890123 25}, got %+v", httprm)
+	}
+}
+
+func TestSchemaEntriesWithSingleEntry(t *testing.T) {
+	// Test that a chunk holding a single entry is treated as one entry
+	entries := SchemaEntries(ImplicitSchema{"di", float64(12)})
+	if len(entries) != 1 || EntryTag(entries[0]) != TagDI {
+		t.Errorf("Expected a single di entry, got %v", entries)
+	}
+}
+
+func TestMarshalResultEnvelope(t *testing.T) {
+	// Test that a result envelope encodes to the batchexecute wire layout
+	envelope := ResultEnvelope{
+		Tag:     TagResult,
+		RPCID:   "abc123",
+		Payload: ImplicitSchema{"test1", 1},
+		Index:   DefaultIndex,
+	}
+
+	data, err := MarshalExplicitSchema(envelope)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+
+	expected := "62\r\n[\"wrb.fr\",\"abc123\",\"[\\\"test1\\\",1]\",null,null,null,\"generic\"]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+}
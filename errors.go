@@ -0,0 +1,297 @@
+package beschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SyntaxError describes malformed input: a stream or chunk that does not follow
+// the "size\r\nJSON_data\r\n" format, or JSON data that cannot be parsed.
+type SyntaxError struct {
+	// Msg describes the problem, e.g. "invalid size format"
+	Msg string
+	// Field is the full field path of an embedded JSON field, e.g. Entity.Sub2.Field1
+	Field string
+	// Index is the array index path of an embedded JSON field, e.g. [2][0]
+	Index string
+	// Chunk is the 0-based chunk number in the stream, or -1 if unknown
+	Chunk int
+	// Offset is the byte offset in the input where the problem was found, or -1 if unknown
+	Offset int64
+	// Err is the underlying cause, if any
+	Err error
+}
+
+// Error implements the error interface.
+func (e *SyntaxError) Error() string {
+	msg := e.Msg + location(e.Field, e.Index, e.Chunk, e.Offset)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying cause.
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// SizeMismatchError reports a chunk whose size header does not match the size of its data.
+type SizeMismatchError struct {
+	Expected int
+	Actual   int
+	// Chunk is the 0-based chunk number in the stream, or -1 if unknown
+	Chunk int
+	// Offset is the byte offset of the size header in the input, or -1 if unknown
+	Offset int64
+}
+
+// Error implements the error interface.
+func (e *SizeMismatchError) Error() string {
+	return fmt.Sprintf("data size mismatch%s: expected %d, got %d", location("", "", e.Chunk, e.Offset), e.Expected, e.Actual)
+}
+
+// TypeMismatchError reports an array value that cannot be stored in a struct field.
+type TypeMismatchError struct {
+	// Value describes the JSON value, e.g. "string", "number" or "array"
+	Value string
+	// Type is the Go type the value could not be stored in
+	Type reflect.Type
+	// Field is the full field path, e.g. Entity.Sub2.Field1
+	Field string
+	// Index is the array index path, e.g. [2][0]
+	Index string
+	// Chunk is the 0-based chunk number in the stream, or -1 if unknown
+	Chunk int
+	// Err is the underlying cause, if any
+	Err error
+}
+
+// Error implements the error interface.
+func (e *TypeMismatchError) Error() string {
+	msg := fmt.Sprintf("cannot unmarshal %s into %s", e.Value, e.Type)
+	msg += location(e.Field, e.Index, e.Chunk, -1)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying cause.
+func (e *TypeMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// UnknownSlotError reports a non-null array value at an index that no struct field maps to.
+// It is only returned when DecodeOptions.DisallowUnknownSlots is set.
+type UnknownSlotError struct {
+	// Value describes the JSON value, e.g. "string", "number" or "array"
+	Value string
+	// Type is the struct type that has no field for the value
+	Type reflect.Type
+	// Field is the full field path of the struct, e.g. Entity.Sub2
+	Field string
+	// Index is the array index path of the value, e.g. [2][3]
+	Index string
+	// Chunk is the 0-based chunk number in the stream, or -1 if unknown
+	Chunk int
+}
+
+// Error implements the error interface.
+func (e *UnknownSlotError) Error() string {
+	return fmt.Sprintf("unexpected %s in %s", e.Value, e.Type) + location(e.Field, e.Index, e.Chunk, -1)
+}
+
+// InvalidTagError reports a beschema tag that cannot be compiled into a field layout, e.g. an index
+// that is not a positive number or an index used by two fields. It is returned the first time
+// a struct type with the tag is encoded or decoded.
+type InvalidTagError struct {
+	// Type is the struct type declaring the field
+	Type reflect.Type
+	// Field is the name of the field
+	Field string
+	// Tag is the value of the beschema tag
+	Tag string
+	// Msg describes the problem, e.g. "index \"abc\" is not a number"
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *InvalidTagError) Error() string {
+	return fmt.Sprintf("invalid beschema tag %q on %s.%s: %s", e.Tag, e.Type, e.Field, e.Msg)
+}
+
+// newSyntaxError creates a SyntaxError with an unknown location.
+func newSyntaxError(msg string, err error) *SyntaxError {
+	return &SyntaxError{Msg: msg, Chunk: -1, Offset: -1, Err: err}
+}
+
+// newJSONSyntaxError creates a SyntaxError for JSON data starting at the given byte offset.
+// The offset of the JSON error itself is added when it is known.
+func newJSONSyntaxError(msg string, err error, offset int64) *SyntaxError {
+	var jsonErr *json.SyntaxError
+	if errors.As(err, &jsonErr) && offset >= 0 {
+		offset += jsonErr.Offset
+	}
+	return &SyntaxError{Msg: msg, Chunk: -1, Offset: offset, Err: err}
+}
+
+// newInvalidTagError creates an InvalidTagError for a field of a struct type.
+func newInvalidTagError(typ reflect.Type, field, tag, msg string) *InvalidTagError {
+	return &InvalidTagError{Type: typ, Field: field, Tag: tag, Msg: msg}
+}
+
+// newTypeMismatchError creates a TypeMismatchError for a value and the type it could not be stored in.
+func newTypeMismatchError(value interface{}, typ reflect.Type, err error) *TypeMismatchError {
+	return &TypeMismatchError{Value: jsonTypeName(value), Type: typ, Chunk: -1, Err: err}
+}
+
+// newUnknownSlotError creates an UnknownSlotError for a value at an index of an array decoded into typ.
+func newUnknownSlotError(value interface{}, typ reflect.Type, index int) *UnknownSlotError {
+	return &UnknownSlotError{Value: jsonTypeName(value), Type: typ, Index: fmt.Sprintf("[%d]", index), Chunk: -1}
+}
+
+// jsonTypeName describes a decoded JSON value the way encoding/json does in its errors.
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// location formats the known parts of an error location, e.g. " at Entity.Sub2 [2] (chunk 1, offset 12)".
+func location(field, index string, chunk int, offset int64) string {
+	var parts []string
+	if field != "" || index != "" {
+		parts = append(parts, strings.TrimSpace(" at "+field+" "+index))
+	}
+	var stream []string
+	if chunk >= 0 {
+		stream = append(stream, fmt.Sprintf("chunk %d", chunk))
+	}
+	if offset >= 0 {
+		stream = append(stream, fmt.Sprintf("offset %d", offset))
+	}
+	if len(stream) > 0 {
+		parts = append(parts, "("+strings.Join(stream, ", ")+")")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, " ")
+}
+
+// pathError is implemented by errors that record where in a schema they occurred.
+type pathError interface {
+	error
+	addPath(field string, index int)
+}
+
+// addPath prepends a field name and an array index to the error path.
+// An empty field or a negative index leaves that part of the path unchanged.
+func (e *SyntaxError) addPath(field string, index int) {
+	e.Field, e.Index = prependPath(e.Field, e.Index, field, index)
+}
+
+// addPath prepends a field name and an array index to the error path.
+// An empty field or a negative index leaves that part of the path unchanged.
+func (e *TypeMismatchError) addPath(field string, index int) {
+	e.Field, e.Index = prependPath(e.Field, e.Index, field, index)
+}
+
+// addPath prepends a field name and an array index to the error path.
+// An empty field or a negative index leaves that part of the path unchanged.
+func (e *UnknownSlotError) addPath(field string, index int) {
+	e.Field, e.Index = prependPath(e.Field, e.Index, field, index)
+}
+
+// prependPath prepends a field name and an array index to a field path and an index path.
+func prependPath(fieldPath, indexPath, field string, index int) (string, string) {
+	if field != "" {
+		if fieldPath == "" {
+			fieldPath = field
+		} else {
+			fieldPath = field + "." + fieldPath
+		}
+	}
+	if index >= 0 {
+		indexPath = fmt.Sprintf("[%d]%s", index, indexPath)
+	}
+	return fieldPath, indexPath
+}
+
+// withPath records a field name and an array index on err if it supports paths,
+// and wraps it with the field name otherwise.
+func withPath(err error, field string, index int) error {
+	if field == "" && index < 0 {
+		return err
+	}
+
+	var pe pathError
+	if errors.As(err, &pe) {
+		pe.addPath(field, index)
+		return err
+	}
+	if field != "" {
+		return fmt.Errorf("failed to set field %s: %w", field, err)
+	}
+	return fmt.Errorf("failed to set index %d: %w", index, err)
+}
+
+// streamError is implemented by errors that record where in a stream they occurred.
+type streamError interface {
+	error
+	locate(chunk int, offset int64)
+}
+
+// locate records the chunk number and shifts the offset by the start of the chunk.
+func (e *SyntaxError) locate(chunk int, offset int64) {
+	e.Chunk = chunk
+	if e.Offset >= 0 {
+		e.Offset += offset
+	}
+}
+
+// locate records the chunk number and shifts the offset by the start of the chunk.
+func (e *SizeMismatchError) locate(chunk int, offset int64) {
+	e.Chunk = chunk
+	if e.Offset >= 0 {
+		e.Offset += offset
+	}
+}
+
+// locate records the chunk number; type mismatches have no byte offset.
+func (e *TypeMismatchError) locate(chunk int, _ int64) {
+	e.Chunk = chunk
+}
+
+// locate records the chunk number; unknown slots have no byte offset.
+func (e *UnknownSlotError) locate(chunk int, _ int64) {
+	e.Chunk = chunk
+}
+
+// withLocation records the chunk number and offset on err if it supports stream locations,
+// and wraps it with the chunk number otherwise.
+func withLocation(err error, chunk int, offset int64) error {
+	var se streamError
+	if errors.As(err, &se) {
+		se.locate(chunk, offset)
+		return err
+	}
+	return fmt.Errorf("failed to parse chunk %d: %w", chunk, err)
+}
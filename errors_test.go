@@ -0,0 +1,160 @@
+package beschema
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Test structs for type mismatches deep inside a schema
+type ErrorLeaf struct {
+	Field1 string       `beschema:"1"`
+	Field2 []SubEntity1 `beschema:"2"`
+}
+
+type ErrorEntity struct {
+	Sub1 SubEntity1 `beschema:"1"`
+	Sub2 ErrorLeaf  `beschema:"3"`
+}
+
+func TestTypeMismatchErrorPath(t *testing.T) {
+	// Test that a type mismatch carries the full field path and array index path
+	data := []byte(`[["test1","test2"],null,["test5",[["a","b"],"oops"]]]`)
+
+	_, err := UnmarshalExplicitSchemaWithOptions[ErrorEntity](data, false, DecodeOptions{Strict: true})
+
+	var typeErr *TypeMismatchError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected *TypeMismatchError, got %T: %v", err, err)
+	}
+
+	if typeErr.Field != "ErrorEntity.Sub2.Field2" {
+		t.Errorf("Expected field path ErrorEntity.Sub2.Field2, got %s", typeErr.Field)
+	}
+	if typeErr.Index != "[2][1][1]" {
+		t.Errorf("Expected index path [2][1][1], got %s", typeErr.Index)
+	}
+	if typeErr.Value != "string" || typeErr.Type != reflect.TypeOf(SubEntity1{}) {
+		t.Errorf("Expected string into SubEntity1, got %s into %s", typeErr.Value, typeErr.Type)
+	}
+
+	expected := "cannot unmarshal string into beschema.SubEntity1 at ErrorEntity.Sub2.Field2 [2][1][1]"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+}
+
+func TestTypeMismatchErrorForTopLevelStruct(t *testing.T) {
+	// Test that a non-array value for a top-level struct field is reported with its path
+	data := []byte(`["not an array"]`)
+
+	_, err := UnmarshalExplicitSchemaWithOptions[ErrorEntity](data, false, DecodeOptions{Strict: true})
+
+	var typeErr *TypeMismatchError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected *TypeMismatchError, got %T: %v", err, err)
+	}
+	if typeErr.Field != "ErrorEntity.Sub1" || typeErr.Index != "[0]" || typeErr.Value != "string" {
+		t.Errorf("Expected string at ErrorEntity.Sub1 [0], got %+v", typeErr)
+	}
+}
+
+func TestSizeMismatchErrorInStream(t *testing.T) {
+	// Test that a size mismatch reports the chunk number and byte offset of its size header
+	streamData := []byte(")]}'\r\n\r\n19\r\n[\"test1\",\"test2\"]\r\n20\r\n[\"test\"]\r\n")
+
+	_, err := UnmarshalImplicitStream(streamData)
+
+	var sizeErr *SizeMismatchError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("Expected *SizeMismatchError, got %T: %v", err, err)
+	}
+	if sizeErr.Chunk != 1 || sizeErr.Offset != 31 {
+		t.Errorf("Expected chunk 1 at offset 31, got chunk %d at offset %d", sizeErr.Chunk, sizeErr.Offset)
+	}
+	if sizeErr.Expected != 20 || sizeErr.Actual != 10 {
+		t.Errorf("Expected 20 != 10, got %d != %d", sizeErr.Expected, sizeErr.Actual)
+	}
+}
+
+func TestSyntaxErrorUnwrap(t *testing.T) {
+	// Test that syntax errors expose their cause through errors.As
+	_, err := UnmarshalImplicitSchema([]byte("abc\r\n[\"test\"]\r\n"), true)
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected *SyntaxError, got %T: %v", err, err)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("Expected the cause to be a *strconv.NumError, got %v", syntaxErr.Err)
+	}
+}
+
+func TestSyntaxErrorOffsetInDecoder(t *testing.T) {
+	// Test that malformed JSON is reported at its byte offset in the stream
+	decoder := NewDecoder(strings.NewReader(")]}'\r\n\r\n18\r\n[\"test\",invalid]\r\n"))
+
+	_, err := decoder.Next()
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected *SyntaxError, got %T: %v", err, err)
+	}
+	if syntaxErr.Chunk != 0 || syntaxErr.Offset != 21 {
+		t.Errorf("Expected chunk 0 at offset 21, got chunk %d at offset %d", syntaxErr.Chunk, syntaxErr.Offset)
+	}
+}
+
+func TestSyntaxErrorForTruncatedChunk(t *testing.T) {
+	// Test that a truncated chunk unwraps to io.ErrUnexpectedEOF
+	decoder := NewDecoder(strings.NewReader(")]}'\r\n\r\n20\r\n"))
+
+	_, err := decoder.Next()
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestTypeMismatchErrorChunkInDecoder(t *testing.T) {
+	// Test that type mismatches found while decoding a stream carry their chunk number
+	decoder := NewDecoder(strings.NewReader(")]}'\r\n\r\n19\r\n[\"test1\",\"test2\"]\r\n11\r\n[\"a\",\"b\"]\r\n"))
+
+	var entity ErrorLeaf
+	if err := decoder.Decode(&entity); err == nil {
+		t.Fatalf("Expected error for first chunk, got nil")
+	}
+	err := decoder.Decode(&entity)
+
+	var typeErr *TypeMismatchError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected *TypeMismatchError, got %T: %v", err, err)
+	}
+	if typeErr.Chunk != 1 || typeErr.Field != "ErrorLeaf.Field2" {
+		t.Errorf("Expected ErrorLeaf.Field2 at chunk 1, got %s at chunk %d", typeErr.Field, typeErr.Chunk)
+	}
+}
+
+func TestUnknownSlotErrorInDecoder(t *testing.T) {
+	// Test that strict decoders report unexpected slots with their path and chunk number
+	decoder := NewDecoder(strings.NewReader(")]}'\r\n\r\n19\r\n[\"test1\",\"test2\"]\r\n15\r\n[\"a\",\"b\",\"c\"]\r\n"))
+	decoder.SetDecodeOptions(DecodeOptions{Strict: true, DisallowUnknownSlots: true})
+
+	var entity SubEntity1
+	if err := decoder.Decode(&entity); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	err := decoder.Decode(&entity)
+
+	var slotErr *UnknownSlotError
+	if !errors.As(err, &slotErr) {
+		t.Fatalf("Expected *UnknownSlotError, got %T: %v", err, err)
+	}
+	expected := "unexpected string in beschema.SubEntity1 at SubEntity1 [2] (chunk 1)"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
@@ -1,20 +1,36 @@
 package beschema
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
-	"sort"
 	"strconv"
-	"strings"
 )
 
+// EncodeOptions controls how chunks are written.
+// The zero value is the default used by MarshalExplicitSchema and MarshalImplicitSchema.
+type EncodeOptions struct {
+	// SizeMode is the unit in which size headers count the JSON data
+	SizeMode SizeMode
+	// TrimTrailingNulls drops the trailing nulls of every array, e.g. ["x",null,null] becomes ["x"],
+	// the way Google's own clients write requests
+	TrimTrailingNulls bool
+}
+
 // MarshalExplicitSchema converts a struct to a byte array following the explicit schema format.
 // It converts the struct to an array representation, marshals it to JSON,
 // and prepends size information in the format: "size\r\nJSON_data\r\n".
 func MarshalExplicitSchema[T any](v T) ([]byte, error) {
+	return MarshalExplicitSchemaWithOptions(v, EncodeOptions{})
+}
+
+// MarshalExplicitSchemaWithOptions is like MarshalExplicitSchema but encodes with the given options,
+// e.g. EncodeOptions{SizeMode: SizeUTF16} to count the size header in UTF-16 code units.
+func MarshalExplicitSchemaWithOptions[T any](v T, opts EncodeOptions) ([]byte, error) {
 	// Convert struct to array
-	arr, err := structToArray(v)
+	arr, err := structToArray(v, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -25,28 +41,49 @@ func MarshalExplicitSchema[T any](v T) ([]byte, error) {
 		return nil, err
 	}
 
-	// Combine with size information (JSON data + \r\n 2 bytes)
-	size := len(jsonData) + 2
+	// Combine with size information (JSON data + \r\n)
+	size := chunkSize(jsonData, opts.SizeMode)
 	result := fmt.Sprintf("%d\r\n%s\r\n", size, string(jsonData))
 
 	return []byte(result), nil
 }
 
+// DecodeOptions controls how array values are stored in struct fields.
+// The zero value is the lenient default used by UnmarshalExplicitSchema.
+type DecodeOptions struct {
+	// Strict rejects values that would otherwise be converted with data loss or ignored:
+	// strings that do not parse as numbers or booleans, fractional numbers for integer fields,
+	// non-string values for string fields, non-array values for nested structs, and extra
+	// elements for fixed-size arrays. Numbers out of range of their field are rejected in every mode.
+	Strict bool
+	// DisallowUnknownSlots rejects non-null array values at indexes that no struct field maps to.
+	DisallowUnknownSlots bool
+	// SizeMode is the unit in which size headers count the JSON data.
+	// SizeAuto and SizeLenient accept headers that do not count bytes.
+	SizeMode SizeMode
+}
+
 // UnmarshalExplicitSchema parses byte data in an explicit schema format and converts it to the specified struct type.
 // The input data should be in the format: "size\r\nJSON_data\r\n".
 // It validates the size information and converts the JSON array back to the target struct.
 func UnmarshalExplicitSchema[T any](data []byte, withHeader bool) (T, error) {
+	return UnmarshalExplicitSchemaWithOptions[T](data, withHeader, DecodeOptions{})
+}
+
+// UnmarshalExplicitSchemaWithOptions is like UnmarshalExplicitSchema but decodes with the given options,
+// e.g. DecodeOptions{Strict: true} to fail on schema drift instead of silently dropping data.
+func UnmarshalExplicitSchemaWithOptions[T any](data []byte, withHeader bool, opts DecodeOptions) (T, error) {
 	var result T
 
 	if !withHeader {
 		// Handle data without header - direct JSON parsing
 		var arr []interface{}
-		if err := json.Unmarshal(data, &arr); err != nil {
-			return result, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		if err := unmarshalJSON(data, &arr); err != nil {
+			return result, newJSONSyntaxError("failed to unmarshal JSON", err, 0)
 		}
 
 		// Convert array to struct
-		if err := arrayToStruct(arr, &result); err != nil {
+		if err := arrayToStruct(arr, &result, opts); err != nil {
 			return result, err
 		}
 
@@ -54,143 +91,185 @@ func UnmarshalExplicitSchema[T any](data []byte, withHeader bool) (T, error) {
 	}
 
 	// Handle data with header (original behavior)
-	// Convert entire data to string
-	dataStr := string(data)
-
-	// Split by \r\n (Windows-style line breaks)
-	lines := strings.Split(dataStr, "\r\n")
-	if len(lines) < 2 {
-		// Try splitting by \n only (Unix-style line breaks)
-		lines = strings.Split(dataStr, "\n")
-		if len(lines) < 2 {
-			return result, fmt.Errorf("invalid data format: expected at least 2 lines")
-		}
-	}
-
-	// Parse size information from the first line
-	expectedSize, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	jsonData, offset, err := splitChunk(data, opts.SizeMode)
 	if err != nil {
-		return result, fmt.Errorf("invalid size format: %v", err)
-	}
-
-	// Parse actual JSON data from the second line
-	jsonData := strings.TrimSpace(lines[1])
-
-	// Actual data size is JSON data + \r\n (2 bytes)
-	actualSize := len(jsonData) + 2
-	if actualSize != expectedSize {
-		return result, fmt.Errorf("data size mismatch: expected %d, got %d (JSON: %d + CRLF: 2)", expectedSize, actualSize, len(jsonData))
+		return result, err
 	}
 
 	// Unmarshal to JSON array
 	var arr []interface{}
-	if err := json.Unmarshal([]byte(jsonData), &arr); err != nil {
-		return result, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	if err := unmarshalJSON(jsonData, &arr); err != nil {
+		return result, newJSONSyntaxError("failed to unmarshal JSON", err, offset)
 	}
 
 	// Convert array to struct
-	if err := arrayToStruct(arr, &result); err != nil {
+	if err := arrayToStruct(arr, &result, opts); err != nil {
 		return result, err
 	}
 
 	return result, nil
 }
 
-// fieldInfo holds information about a struct field and its beschema tag
-type fieldInfo struct {
-	field     reflect.Value
-	fieldType reflect.StructField
-	tagValue  int
-}
-
 // structToArray is a helper function that converts a struct to an array representation.
 // It recursively processes nested structs and handles unexported fields appropriately.
 // Fields are ordered by their beschema tag values.
-func structToArray(v interface{}) ([]interface{}, error) {
+func structToArray(v interface{}, opts EncodeOptions) ([]interface{}, error) {
 	val := reflect.ValueOf(v)
-	typ := reflect.TypeOf(v)
 
 	// Dereference if it's a pointer
 	if val.Kind() == reflect.Ptr {
 		val = val.Elem()
-		typ = typ.Elem()
 	}
 
 	if val.Kind() != reflect.Struct {
 		return nil, fmt.Errorf("expected struct, got %s", val.Kind())
 	}
 
-	// Collect field information with beschema tags
-	var fields []fieldInfo
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		fieldType := typ.Field(i)
+	return encodeStruct(val, opts)
+}
 
-		// Skip unexported fields
-		if !field.CanInterface() {
-			continue
-		}
+// encodeStruct is a helper function that converts a struct value to an array representation
+// using the cached field layout of its type. Fields tagged with the omitempty option are
+// encoded as null if they hold an empty value.
+func encodeStruct(val reflect.Value, opts EncodeOptions) ([]interface{}, error) {
+	// Look up the cached field layout of the struct type
+	plan, err := cachedPlan(val.Type())
+	if err != nil {
+		return nil, err
+	}
 
-		// Parse beschema tag
-		tagValue := i + 1 // default to field order (1-based)
-		if tag := fieldType.Tag.Get("beschema"); tag != "" {
-			if parsedTag, err := strconv.Atoi(tag); err == nil {
-				tagValue = parsedTag
+	// Create result array with proper size, initialized with nulls
+	size := plan.size
+	var extra reflect.Value
+	if plan.extra != nil {
+		extra, _ = fieldByIndex(val, plan.extra, false) // Invalid if an embedded pointer on the way is nil
+	}
+	if extra.IsValid() {
+		for _, key := range extra.MapKeys() {
+			// The array is as long as the largest key, so keys are bounded like those of indexed maps
+			if key.Int() > maxMapIndex {
+				return nil, fmt.Errorf("extra index %d exceeds the maximum of %d", key.Int(), maxMapIndex)
+			}
+			if index := int(key.Int()); index >= size {
+				size = index + 1
 			}
 		}
-
-		fields = append(fields, fieldInfo{
-			field:     field,
-			fieldType: fieldType,
-			tagValue:  tagValue,
-		})
 	}
-
-	// Sort fields by beschema tag value
-	sort.Slice(fields, func(i, j int) bool {
-		return fields[i].tagValue < fields[j].tagValue
-	})
-
-	// Find the maximum tag value to determine array size
-	maxTagValue := 0
-	for _, fieldInfo := range fields {
-		if fieldInfo.tagValue > maxTagValue {
-			maxTagValue = fieldInfo.tagValue
+	result := make([]interface{}, size)
+
+	// Write back the collected elements at the indexes no field maps to
+	if extra.IsValid() {
+		iter := extra.MapRange()
+		for iter.Next() {
+			index := int(iter.Key().Int())
+			if index < 0 || plan.hasSlot(index) {
+				continue // Fields take precedence over collected elements
+			}
+			value, err := encodeValue(iter.Value(), opts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert extra index %d: %w", index, err)
+			}
+			result[index] = value
 		}
 	}
 
-	// Create result array with proper size, initialized with nulls
-	result := make([]interface{}, maxTagValue)
-	for i := range result {
-		result[i] = nil
-	}
-
 	// Place each field at its correct index (tagValue - 1)
-	for _, fieldInfo := range fields {
-		arrayIndex := fieldInfo.tagValue - 1 // Convert 1-based tag to 0-based array index
+	for _, fp := range plan.fields {
+		arrayIndex := fp.tagValue - 1 // Convert 1-based tag to 0-based array index
 		if arrayIndex < 0 || arrayIndex >= len(result) {
 			continue // Skip if tag value is out of bounds
 		}
 
-		// If a field is a struct, process recursively
-		if fieldInfo.field.Kind() == reflect.Struct {
-			subArray, err := structToArray(fieldInfo.field.Interface())
-			if err != nil {
-				return nil, fmt.Errorf("failed to convert field %s: %v", fieldInfo.fieldType.Name, err)
-			}
-			result[arrayIndex] = subArray
+		field, ok := fieldByIndex(val, fp.index, false)
+		if !ok {
+			continue // Left as null if an embedded pointer on the way is nil
+		}
+		if fp.options.omitempty && isEmptyValue(field) {
+			continue // Left as null
+		}
+
+		// Nested structs, slices and arrays are processed recursively
+		var value interface{}
+		var err error
+		if fp.options.pairs || fp.options.indexed {
+			value, err = encodeMap(field, fp.options, opts)
 		} else {
-			result[arrayIndex] = fieldInfo.field.Interface()
+			value, err = encodeValue(field, opts)
+		}
+		if err == nil && fp.options.json {
+			value, err = encodeEmbeddedJSON(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert field %s: %w", fp.name, err)
 		}
+		result[arrayIndex] = value
+	}
+
+	if opts.TrimTrailingNulls {
+		result = trimNulls(result)
 	}
 
 	return result, nil
 }
 
+// encodeValue is a helper function that converts a field value to its array representation.
+// Types implementing BeschemaMarshaler, json.Marshaler or encoding.TextMarshaler encode themselves.
+// Structs become arrays ordered by their beschema tags, byte slices become base64 strings,
+// other slices and arrays become arrays of encoded elements, nil pointers and maps become null,
+// interfaces are encoded by their dynamic value, and any other value is returned as is.
+func encodeValue(val reflect.Value, opts EncodeOptions) (interface{}, error) {
+	// Types with a custom encoding take precedence at any nesting depth
+	if value, ok, err := marshalCustom(val); ok {
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", val.Type(), err)
+		}
+		return value, nil
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		return encodeStruct(val, opts)
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(val.Elem(), opts)
+	case reflect.Slice:
+		if val.IsNil() {
+			return nil, nil
+		}
+		// Binary data is sent as a base64 string, like encoding/json does
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(val.Bytes()), nil
+		}
+		fallthrough
+	case reflect.Array:
+		result := make([]interface{}, val.Len())
+		for i := 0; i < val.Len(); i++ {
+			elem, err := encodeValue(val.Index(i), opts)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert index %d: %w", i, err)
+			}
+			result[i] = elem
+		}
+		if opts.TrimTrailingNulls {
+			result = trimNulls(result)
+		}
+		return result, nil
+	case reflect.Map:
+		if val.IsNil() {
+			return nil, nil
+		}
+		return val.Interface(), nil
+	default:
+		return val.Interface(), nil
+	}
+}
+
 // arrayToStruct is a helper function that converts an array to a struct.
 // The target parameter must be a pointer to the struct to be populated.
 // Fields are mapped based on their beschema tag values.
-func arrayToStruct(arr []interface{}, target interface{}) error {
+func arrayToStruct(arr []interface{}, target interface{}, opts DecodeOptions) error {
 	val := reflect.ValueOf(target)
 	if val.Kind() != reflect.Ptr {
 		return fmt.Errorf("target must be a pointer")
@@ -203,125 +282,306 @@ func arrayToStruct(arr []interface{}, target interface{}) error {
 		return fmt.Errorf("target must be a pointer to struct")
 	}
 
-	// Collect field information with beschema tags
-	var fields []fieldInfo
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-		fieldType := typ.Field(i)
-
-		// Skip unexported fields
-		if !field.CanSet() {
-			continue
+	if err := populateStructFromArray(val, arr, opts); err != nil {
+		// Invalid tags are reported as is, the type is part of the error
+		var tagErr *InvalidTagError
+		if errors.As(err, &tagErr) {
+			return err
 		}
+		// The error path starts at the root struct type, e.g. Entity.Sub2.Field1 [2][0]
+		return withPath(err, typ.Name(), -1)
+	}
 
-		// Parse beschema tag
-		tagValue := i + 1 // default to field order (1-based)
-		if tag := fieldType.Tag.Get("beschema"); tag != "" {
-			if parsedTag, err := strconv.Atoi(tag); err == nil {
-				tagValue = parsedTag
-			}
-		}
+	return nil
+}
 
-		fields = append(fields, fieldInfo{
-			field:     field,
-			fieldType: fieldType,
-			tagValue:  tagValue,
-		})
+// populateStructFromArray is a helper function that populates struct fields from an array.
+// It handles nested structs recursively and converts array elements to appropriate field types.
+// Fields are mapped based on their beschema tag values.
+func populateStructFromArray(structVal reflect.Value, arr []interface{}, opts DecodeOptions) error {
+	// Look up the cached field layout of the struct type
+	plan, err := cachedPlan(structVal.Type())
+	if err != nil {
+		return err
 	}
 
-	// Sort fields by beschema tag value
-	sort.Slice(fields, func(i, j int) bool {
-		return fields[i].tagValue < fields[j].tagValue
-	})
+	if plan.extra != nil {
+		extra, _ := fieldByIndex(structVal, plan.extra, true)
+		collectExtra(extra, plan, arr)
+	} else if opts.DisallowUnknownSlots {
+		if err := checkUnknownSlots(plan, arr, structVal.Type()); err != nil {
+			return err
+		}
+	}
 
 	// Map array elements to fields based on tag values (1-based to 0-based conversion)
-	for _, fieldInfo := range fields {
-		arrayIndex := fieldInfo.tagValue - 1 // Convert 1-based tag to 0-based array index
+	for _, fp := range plan.fields {
+		arrayIndex := fp.tagValue - 1 // Convert 1-based tag to 0-based array index
 		if arrayIndex < 0 || arrayIndex >= len(arr) {
 			continue // Skip if tag value is out of bounds
 		}
 
 		arrValue := arr[arrayIndex]
+		field, ok := fieldByIndex(structVal, fp.index, arrValue != nil)
+		if !ok {
+			continue // A nil embedded pointer is left nil for a null value
+		}
 
-		// If the field is a struct
-		if fieldInfo.field.Kind() == reflect.Struct {
-			// Check if array data is a slice
-			if subArr, ok := arrValue.([]interface{}); ok {
-				// Map each field of the struct with array elements
-				if err := populateStructFromArray(fieldInfo.field, subArr); err != nil {
-					return fmt.Errorf("failed to populate struct field %s: %v", fieldInfo.fieldType.Name, err)
-				}
-			} else {
-				return fmt.Errorf("expected array for struct field %s, got %T", fieldInfo.fieldType.Name, arrValue)
-			}
-		} else {
-			// Set a basic type field
-			if err := setFieldValue(fieldInfo.field, arrValue); err != nil {
-				return fmt.Errorf("failed to set field %s: %v", fieldInfo.fieldType.Name, err)
-			}
+		var err error
+		switch {
+		case fp.options.json:
+			// For embedded JSON documents
+			err = decodeEmbeddedJSON(field, arrValue, opts)
+		case fp.options.pairs || fp.options.indexed:
+			// For maps stored as pairs or by index
+			err = decodeMap(field, arrValue, fp.options, opts)
+		default:
+			// Custom decodings, nested structs, pointers, slices and basic types
+			err = decodeValue(field, arrValue, opts)
+		}
+		if err != nil {
+			return withPath(err, fp.name, arrayIndex)
 		}
 	}
 
 	return nil
 }
 
-// populateStructFromArray is a helper function that populates struct fields from an array.
-// It handles nested structs recursively and converts array elements to appropriate field types.
-// Fields are mapped based on their beschema tag values.
-func populateStructFromArray(structVal reflect.Value, arr []interface{}) error {
-	structType := structVal.Type()
-
-	// Collect field information with beschema tags
-	var fields []fieldInfo
-	for i := 0; i < structVal.NumField(); i++ {
-		field := structVal.Field(i)
-		fieldType := structType.Field(i)
+// checkUnknownSlots is a helper function that rejects the first non-null array element
+// that no field of the struct type maps to.
+func checkUnknownSlots(plan *structPlan, arr []interface{}, typ reflect.Type) error {
+	for i, value := range arr {
+		if value != nil && !plan.hasSlot(i) {
+			return newUnknownSlotError(value, typ, i)
+		}
+	}
+	return nil
+}
 
-		if !field.CanSet() {
+// collectExtra is a helper function that stores the non-null array elements no field maps to
+// in the catch-all field, keyed by their 0-based array index.
+func collectExtra(field reflect.Value, plan *structPlan, arr []interface{}) {
+	for i, value := range arr {
+		if value == nil || plan.hasSlot(i) {
 			continue
 		}
+		if field.IsNil() {
+			field.Set(reflect.MakeMap(field.Type()))
+		}
+		field.SetMapIndex(reflect.ValueOf(i).Convert(field.Type().Key()), reflect.ValueOf(value))
+	}
+}
+
+// isEmptyValue is a helper function that reports whether a field holds an empty value
+// for the omitempty option: false, 0, "", nil, or an empty slice, array or map, like encoding/json.
+func isEmptyValue(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return val.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return val.IsZero()
+	default:
+		return false
+	}
+}
+
+// trimNulls is a helper function that drops the trailing nulls of an array.
+func trimNulls(arr []interface{}) []interface{} {
+	end := len(arr)
+	for end > 0 && arr[end-1] == nil {
+		end--
+	}
+	return arr[:end]
+}
+
+// trimTrailingNulls is a helper function that returns a copy of a decoded JSON value
+// with the trailing nulls of every array dropped, for values that were not produced
+// by encodeValue, such as an ImplicitSchema.
+func trimTrailingNulls(value interface{}) interface{} {
+	var arr []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		arr = v
+	case ImplicitSchema:
+		arr = v
+	default:
+		return value
+	}
+
+	result := make([]interface{}, len(arr))
+	for i, elem := range arr {
+		result[i] = trimTrailingNulls(elem)
+	}
+	return trimNulls(result)
+}
+
+// encodeEmbeddedJSON is a helper function that encodes an already converted value
+// into a JSON string, for fields tagged with the json option. null stays null.
+func encodeEmbeddedJSON(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal embedded JSON: %w", err)
+	}
+
+	return string(jsonData), nil
+}
+
+// decodeEmbeddedJSON is a helper function that parses a JSON string array element
+// and decodes the result into a field tagged with the json option.
+func decodeEmbeddedJSON(field reflect.Value, value interface{}, opts DecodeOptions) error {
+	if value == nil {
+		return decodeValue(field, nil, opts)
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return newTypeMismatchError(value, field.Type(), errors.New("expected JSON string"))
+	}
+
+	var parsed interface{}
+	if err := unmarshalJSON([]byte(str), &parsed); err != nil {
+		return newJSONSyntaxError("failed to unmarshal embedded JSON", err, -1)
+	}
+
+	return decodeValue(field, parsed, opts)
+}
+
+// decodeValue is a helper function that sets a value from an array element.
+// Types implementing BeschemaUnmarshaler, json.Unmarshaler or encoding.TextUnmarshaler decode themselves.
+// Nested arrays are converted to structs, slices and arrays recursively,
+// pointers are allocated for non-null values, interfaces hold the value as is,
+// and any other value is handled by setFieldValue.
+func decodeValue(field reflect.Value, value interface{}, opts DecodeOptions) error {
+	// Types with a custom decoding take precedence at any nesting depth;
+	// pointers are allocated first so that their element can decode itself
+	if field.Kind() != reflect.Ptr {
+		if ok, err := unmarshalCustom(field, value); ok {
+			return err
+		}
+	}
 
-		// Parse beschema tag
-		tagValue := i + 1 // default to field order (1-based)
-		if tag := fieldType.Tag.Get("beschema"); tag != "" {
-			if parsedTag, err := strconv.Atoi(tag); err == nil {
-				tagValue = parsedTag
-			}
+	switch field.Kind() {
+	case reflect.Struct:
+		if value == nil || ignoresValue(field.Type(), value, opts) {
+			return nil
+		}
+		subArr, ok := value.([]interface{})
+		if !ok {
+			return newTypeMismatchError(value, field.Type(), nil)
+		}
+		return populateStructFromArray(field, subArr, opts)
+	case reflect.Ptr:
+		// null means absent, so it is kept apart from a pointer to the zero value
+		if value == nil {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		if ignoresValue(field.Type().Elem(), value, opts) {
+			return nil
+		}
+		elem := reflect.New(field.Type().Elem())
+		if err := decodeValue(elem.Elem(), value, opts); err != nil {
+			return err
 		}
+		field.Set(elem)
+		return nil
+	case reflect.Slice, reflect.Array:
+		return decodeSlice(field, value, opts)
+	case reflect.Interface:
+		return decodeInterface(field, value, opts)
+	default:
+		return setFieldValue(field, value, opts)
+	}
+}
+
+// decodeInterface is a helper function that stores an array element in an interface field as is,
+// so that an any field keeps the raw sub-tree ([]any, string, json.Number, bool or nil).
+// If the field already holds a non-nil pointer, the element is decoded into the value it points to,
+// like encoding/json does; this is the only way to decode into an interface type with methods,
+// which raw values do not implement.
+func decodeInterface(field reflect.Value, value interface{}, opts DecodeOptions) error {
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
 
-		fields = append(fields, fieldInfo{
-			field:     field,
-			fieldType: fieldType,
-			tagValue:  tagValue,
-		})
+	if elem := field.Elem(); elem.Kind() == reflect.Ptr && !elem.IsNil() {
+		return decodeValue(elem.Elem(), value, opts)
 	}
 
-	// Sort fields by beschema tag value
-	sort.Slice(fields, func(i, j int) bool {
-		return fields[i].tagValue < fields[j].tagValue
-	})
+	fieldType := field.Type()
+	valueType := reflect.TypeOf(value)
+	if !valueType.Implements(fieldType) {
+		return newTypeMismatchError(value, fieldType, fmt.Errorf("%s does not implement %s", valueType, fieldType))
+	}
+	field.Set(reflect.ValueOf(value))
+	return nil
+}
 
-	// Map array elements to fields based on tag values (1-based to 0-based conversion)
-	for _, fieldInfo := range fields {
-		arrayIndex := fieldInfo.tagValue - 1 // Convert 1-based tag to 0-based array index
-		if arrayIndex < 0 || arrayIndex >= len(arr) {
-			continue // Skip if tag value is out of bounds
+// ignoresValue is a helper function that reports whether a non-array value is ignored when decoded
+// into a struct of type typ: it is left as is in lenient mode and rejected in strict mode.
+func ignoresValue(typ reflect.Type, value interface{}, opts DecodeOptions) bool {
+	_, isArray := value.([]interface{})
+	return typ.Kind() == reflect.Struct && !isArray && !opts.Strict && !hasCustomCodec(typ)
+}
+
+// decodeSlice is a helper function that populates a slice or array from an array element.
+// Slices are resized to the length of the input; arrays keep their fixed length,
+// so extra input elements are dropped and missing ones are left as zero values.
+// Byte slices are also decoded from base64 strings.
+func decodeSlice(field reflect.Value, value interface{}, opts DecodeOptions) error {
+	if value == nil {
+		return nil // Ignore nil values
+	}
+
+	fieldType := field.Type()
+
+	// If types match directly (e.g. []interface{}, or ImplicitSchema which shares its underlying type)
+	valueType := reflect.TypeOf(value)
+	if valueType == fieldType || (fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Interface && valueType.ConvertibleTo(fieldType)) {
+		field.Set(reflect.ValueOf(value).Convert(fieldType))
+		return nil
+	}
+
+	// Binary data is sent as a base64 string
+	if str, ok := value.(string); ok && fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Uint8 {
+		data, err := decodeBase64(str)
+		if err != nil {
+			return newTypeMismatchError(value, fieldType, err)
 		}
+		field.SetBytes(data)
+		return nil
+	}
 
-		arrValue := arr[arrayIndex]
+	arr, ok := value.([]interface{})
+	if !ok {
+		return newTypeMismatchError(value, fieldType, nil)
+	}
 
-		if fieldInfo.field.Kind() == reflect.Struct {
-			// For nested structs
-			if subArr, ok := arrValue.([]interface{}); ok {
-				if err := populateStructFromArray(fieldInfo.field, subArr); err != nil {
-					return fmt.Errorf("failed to populate nested struct field %s: %v", fieldInfo.fieldType.Name, err)
-				}
-			}
-		} else {
-			// Set a basic type field
-			if err := setFieldValue(fieldInfo.field, arrValue); err != nil {
-				return fmt.Errorf("failed to set field %s: %v", fieldInfo.fieldType.Name, err)
-			}
+	if field.Kind() == reflect.Slice {
+		field.Set(reflect.MakeSlice(fieldType, len(arr), len(arr)))
+	} else {
+		field.Set(reflect.Zero(fieldType))
+	}
+
+	if opts.Strict && len(arr) > field.Len() {
+		return newTypeMismatchError(value, fieldType, fmt.Errorf("%d elements do not fit in %d", len(arr), field.Len()))
+	}
+
+	for i, elem := range arr {
+		if i >= field.Len() {
+			break
+		}
+		if err := decodeValue(field.Index(i), elem, opts); err != nil {
+			return withPath(err, "", i)
 		}
 	}
 
@@ -330,8 +590,10 @@ func populateStructFromArray(structVal reflect.Value, arr []interface{}) error {
 
 // setFieldValue is a helper function that sets a field value with an appropriate type conversion.
 // It handles type conversions between interface{} values and struct field types,
-// supporting string, numeric, and boolean types.
-func setFieldValue(field reflect.Value, value interface{}) error {
+// supporting string, numeric, and boolean types. json.Number values are converted exactly;
+// integers out of range of the field are never wrapped. Values that cannot be converted are
+// ignored, unless opts.Strict is set, in which case they are reported as a TypeMismatchError.
+func setFieldValue(field reflect.Value, value interface{}, opts DecodeOptions) error {
 	if value == nil {
 		return nil // Ignore nil values
 	}
@@ -346,26 +608,41 @@ func setFieldValue(field reflect.Value, value interface{}) error {
 	}
 
 	// Type conversion is needed
+	var err error
 	switch fieldType.Kind() {
 	case reflect.String:
 		if str, ok := value.(string); ok {
 			field.SetString(str)
+		} else if opts.Strict {
+			err = errors.New("expected string")
 		} else {
+			// json.Number keeps the exact digits, e.g. of IDs beyond 2^53
 			field.SetString(fmt.Sprintf("%v", value))
 		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		if num, ok := value.(float64); ok {
-			field.SetInt(int64(num))
-		} else if str, ok := value.(string); ok {
-			if intVal, err := strconv.ParseInt(str, 10, 64); err == nil {
+		var intVal int64
+		if intVal, err = toInt64(value, opts.Strict); err == nil {
+			if field.OverflowInt(intVal) {
+				err = &overflowError{value, fieldType.String()}
+			} else {
 				field.SetInt(intVal)
 			}
 		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var uintVal uint64
+		if uintVal, err = toUint64(value, opts.Strict); err == nil {
+			if field.OverflowUint(uintVal) {
+				err = &overflowError{value, fieldType.String()}
+			} else {
+				field.SetUint(uintVal)
+			}
+		}
 	case reflect.Float32, reflect.Float64:
-		if num, ok := value.(float64); ok {
-			field.SetFloat(num)
-		} else if str, ok := value.(string); ok {
-			if floatVal, err := strconv.ParseFloat(str, 64); err == nil {
+		var floatVal float64
+		if floatVal, err = toFloat64(value); err == nil {
+			if field.OverflowFloat(floatVal) {
+				err = &overflowError{value, fieldType.String()}
+			} else {
 				field.SetFloat(floatVal)
 			}
 		}
@@ -373,12 +650,21 @@ func setFieldValue(field reflect.Value, value interface{}) error {
 		if b, ok := value.(bool); ok {
 			field.SetBool(b)
 		} else if str, ok := value.(string); ok {
-			if boolVal, err := strconv.ParseBool(str); err == nil {
+			var boolVal bool
+			if boolVal, err = strconv.ParseBool(str); err == nil {
 				field.SetBool(boolVal)
 			}
+		} else {
+			err = errors.New("expected bool")
 		}
 	default:
-		return fmt.Errorf("unsupported field type: %s", fieldType.Kind())
+		return newTypeMismatchError(value, fieldType, fmt.Errorf("unsupported field type: %s", fieldType.Kind()))
+	}
+
+	// Numbers out of range are reported in every mode, other conversion errors only in strict mode
+	var overflow *overflowError
+	if err != nil && (opts.Strict || errors.As(err, &overflow)) {
+		return newTypeMismatchError(value, fieldType, err)
 	}
 
 	return nil
@@ -2,6 +2,9 @@ package beschema
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -22,7 +25,7 @@ func TestStructToArrayWithBeschemaTag(t *testing.T) {
 	// Expected array should respect beschema tag order: [Field1, Field2]
 	expected := []interface{}{"first", "second"}
 
-	result, err := structToArray(testData)
+	result, err := structToArray(testData, EncodeOptions{})
 	if err != nil {
 		t.Fatalf("structToArray failed: %v", err)
 	}
@@ -43,7 +46,7 @@ func TestArrayToStructWithBeschemaTag(t *testing.T) {
 	arrayData := []interface{}{"first", "second"}
 
 	var result TestStruct
-	err := arrayToStruct(arrayData, &result)
+	err := arrayToStruct(arrayData, &result, DecodeOptions{})
 	if err != nil {
 		t.Fatalf("arrayToStruct failed: %v", err)
 	}
@@ -82,7 +85,7 @@ func TestNestedStructWithBeschemaTag(t *testing.T) {
 	// Nested should be ordered by its tags: [InnerField1, InnerField2]
 	expected := []interface{}{[]interface{}{"inner1", "inner2"}, "outer"}
 
-	result, err := structToArray(testData)
+	result, err := structToArray(testData, EncodeOptions{})
 	if err != nil {
 		t.Fatalf("structToArray failed: %v", err)
 	}
@@ -118,7 +121,7 @@ func TestArrayToNestedStructWithBeschemaTag(t *testing.T) {
 	arrayData := []interface{}{[]interface{}{"inner1", "inner2"}, "outer"}
 
 	var result OuterStruct
-	err := arrayToStruct(arrayData, &result)
+	err := arrayToStruct(arrayData, &result, DecodeOptions{})
 	if err != nil {
 		t.Fatalf("arrayToStruct failed: %v", err)
 	}
@@ -237,7 +240,7 @@ func TestNonSequentialBeschemaTagsIssue(t *testing.T) {
 	}
 
 	var result Entity
-	err := arrayToStruct(arrayData, &result)
+	err := arrayToStruct(arrayData, &result, DecodeOptions{})
 	if err != nil {
 		t.Fatalf("arrayToStruct failed: %v", err)
 	}
@@ -293,7 +296,7 @@ func TestModifiedBeschemaTagsIssue(t *testing.T) {
 	}
 
 	var result EntityModified
-	err := arrayToStruct(arrayData, &result)
+	err := arrayToStruct(arrayData, &result, DecodeOptions{})
 	if err != nil {
 		t.Fatalf("arrayToStruct failed: %v", err)
 	}
@@ -442,3 +445,921 @@ func TestUnmarshalExplicitSchemaWithoutHeader(t *testing.T) {
 		t.Errorf("Expected Field2[0] = 'test4', got %v", result.Field2[0])
 	}
 }
+
+// Test structs for slice and array fields
+type RepeatedItem struct {
+	Name  string `beschema:"1"`
+	Count int    `beschema:"2"`
+}
+
+type RepeatedEntity struct {
+	Tags   []string       `beschema:"1"`
+	Items  []RepeatedItem `beschema:"2"`
+	Matrix [][]int        `beschema:"3"`
+	Pair   [2]float64     `beschema:"4"`
+}
+
+func TestUnmarshalExplicitSchemaWithSliceFields(t *testing.T) {
+	// Test decoding repeated messages into slices of scalars, structs and slices
+	data := []byte(`[["a","b","c"],[["item1",1],["item2",2]],[[1,2],[3]],[1.5,2.5,3.5]]`)
+
+	result, err := UnmarshalExplicitSchema[RepeatedEntity](data, false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+
+	if len(result.Tags) != 3 || result.Tags[0] != "a" || result.Tags[2] != "c" {
+		t.Errorf("Expected Tags = [a b c], got %v", result.Tags)
+	}
+
+	if len(result.Items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(result.Items))
+	}
+	if result.Items[1].Name != "item2" || result.Items[1].Count != 2 {
+		t.Errorf("Expected Items[1] = {item2 2}, got %+v", result.Items[1])
+	}
+
+	if len(result.Matrix) != 2 || len(result.Matrix[0]) != 2 || result.Matrix[1][0] != 3 {
+		t.Errorf("Expected Matrix = [[1 2] [3]], got %v", result.Matrix)
+	}
+
+	// Arrays keep their fixed length, so the extra element is dropped
+	if result.Pair != [2]float64{1.5, 2.5} {
+		t.Errorf("Expected Pair = [1.5 2.5], got %v", result.Pair)
+	}
+}
+
+func TestMarshalUnmarshalExplicitSchemaWithSliceFields(t *testing.T) {
+	// Test complete marshal/unmarshal cycle with slice and array fields
+	original := RepeatedEntity{
+		Tags:   []string{"a", "b"},
+		Items:  []RepeatedItem{{Name: "item1", Count: 1}, {Name: "item2", Count: 2}},
+		Matrix: [][]int{{1, 2}, {3}},
+		Pair:   [2]float64{1.5, 2.5},
+	}
+
+	data, err := MarshalExplicitSchema(original)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+
+	expected := "61\r\n[[\"a\",\"b\"],[[\"item1\",1],[\"item2\",2]],[[1,2],[3]],[1.5,2.5]]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+
+	result, err := UnmarshalExplicitSchema[RepeatedEntity](data, true)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+
+	if len(result.Items) != 2 || result.Items[0] != original.Items[0] || result.Items[1] != original.Items[1] {
+		t.Errorf("Expected Items = %+v, got %+v", original.Items, result.Items)
+	}
+	if len(result.Matrix) != 2 || result.Matrix[0][1] != 2 {
+		t.Errorf("Expected Matrix = %v, got %v", original.Matrix, result.Matrix)
+	}
+	if result.Pair != original.Pair {
+		t.Errorf("Expected Pair = %v, got %v", original.Pair, result.Pair)
+	}
+}
+
+func TestUnmarshalExplicitSchemaWithNullSliceField(t *testing.T) {
+	// Test that null leaves slices nil and null elements as zero values
+	data := []byte(`[null,[null,["item2",2]]]`)
+
+	result, err := UnmarshalExplicitSchema[RepeatedEntity](data, false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+
+	if result.Tags != nil {
+		t.Errorf("Expected nil Tags, got %v", result.Tags)
+	}
+	if len(result.Items) != 2 || result.Items[0] != (RepeatedItem{}) || result.Items[1].Name != "item2" {
+		t.Errorf("Expected Items = [{ 0} {item2 2}], got %+v", result.Items)
+	}
+}
+
+func TestUnmarshalExplicitSchemaWithInvalidSliceElement(t *testing.T) {
+	// Test that a non-array value for a struct element is reported with its index in strict mode
+	data := []byte(`[null,[["item1",1],"oops"]]`)
+
+	_, err := UnmarshalExplicitSchemaWithOptions[RepeatedEntity](data, false, DecodeOptions{Strict: true})
+	if err == nil {
+		t.Fatalf("Expected error for invalid slice element, got nil")
+	}
+
+	var typeErr *TypeMismatchError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected *TypeMismatchError, got %T: %v", err, err)
+	}
+	if typeErr.Field != "RepeatedEntity.Items" || typeErr.Index != "[1][1]" {
+		t.Errorf("Expected error at RepeatedEntity.Items [1][1], got %s %s", typeErr.Field, typeErr.Index)
+	}
+}
+
+// Test structs for pointer fields
+type OptionalEntity struct {
+	Name  *string         `beschema:"1"`
+	Count *int            `beschema:"2"`
+	Sub   *SubEntity1     `beschema:"3"`
+	Items []*RepeatedItem `beschema:"4"`
+	Ptr   **SubEntity2    `beschema:"5"`
+}
+
+func TestUnmarshalExplicitSchemaWithPointerFields(t *testing.T) {
+	// Test that null decodes to nil and present values to allocated pointers
+	data := []byte(`["",null,["test1","test2"],[null,["item",1]],["test5","test6"]]`)
+
+	result, err := UnmarshalExplicitSchema[OptionalEntity](data, false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+
+	// An empty string is present, so it must not be confused with absent
+	if result.Name == nil || *result.Name != "" {
+		t.Errorf("Expected Name to point to empty string, got %v", result.Name)
+	}
+	if result.Count != nil {
+		t.Errorf("Expected nil Count, got %v", *result.Count)
+	}
+	if result.Sub == nil || result.Sub.Field1 != "test1" || result.Sub.Field2 != "test2" {
+		t.Errorf("Expected Sub = {test1 test2}, got %+v", result.Sub)
+	}
+	if len(result.Items) != 2 || result.Items[0] != nil || result.Items[1] == nil || result.Items[1].Name != "item" {
+		t.Errorf("Expected Items = [nil {item 1}], got %v", result.Items)
+	}
+	if result.Ptr == nil || *result.Ptr == nil || (*result.Ptr).Field2 != "test6" {
+		t.Errorf("Expected Ptr to point to {test5 test6}, got %v", result.Ptr)
+	}
+}
+
+func TestMarshalExplicitSchemaWithPointerFields(t *testing.T) {
+	// Test that nil pointers encode to null and others to their values
+	count := 0
+	original := OptionalEntity{
+		Count: &count,
+		Items: []*RepeatedItem{nil, {Name: "item", Count: 1}},
+	}
+
+	data, err := MarshalExplicitSchema(original)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+
+	expected := "38\r\n[null,0,null,[null,[\"item\",1]],null]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+
+	result, err := UnmarshalExplicitSchema[OptionalEntity](data, true)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if result.Name != nil || result.Sub != nil || result.Ptr != nil {
+		t.Errorf("Expected nil Name, Sub and Ptr, got %+v", result)
+	}
+	if result.Count == nil || *result.Count != 0 {
+		t.Errorf("Expected Count to point to 0, got %v", result.Count)
+	}
+}
+
+// Test structs for the json tag option
+type EmbeddedPayload struct {
+	Name  string `beschema:"1"`
+	Count int    `beschema:"2"`
+}
+
+type EmbeddedEntity struct {
+	Field1  string           `beschema:"1"`
+	Raw     ImplicitSchema   `beschema:"3,json"`
+	Payload EmbeddedPayload  `beschema:"4,json"`
+	Items   []string         `beschema:"5,json"`
+	Missing *EmbeddedPayload `beschema:"6,json"`
+}
+
+func TestUnmarshalExplicitSchemaWithEmbeddedJSON(t *testing.T) {
+	// Test that JSON documents encoded as strings are decoded into nested values
+	data := []byte(`["test1",null,"[[null]]","[\"inner\",7]","[\"a\",\"b\"]",null]`)
+
+	result, err := UnmarshalExplicitSchema[EmbeddedEntity](data, false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+
+	if len(result.Raw) != 1 {
+		t.Fatalf("Expected Raw to have 1 element, got %v", result.Raw)
+	}
+	if inner, ok := result.Raw[0].([]interface{}); !ok || len(inner) != 1 || inner[0] != nil {
+		t.Errorf("Expected Raw[0] = [null], got %v", result.Raw[0])
+	}
+	if result.Payload.Name != "inner" || result.Payload.Count != 7 {
+		t.Errorf("Expected Payload = {inner 7}, got %+v", result.Payload)
+	}
+	if len(result.Items) != 2 || result.Items[1] != "b" {
+		t.Errorf("Expected Items = [a b], got %v", result.Items)
+	}
+	if result.Missing != nil {
+		t.Errorf("Expected nil Missing, got %+v", result.Missing)
+	}
+}
+
+func TestMarshalUnmarshalExplicitSchemaWithEmbeddedJSON(t *testing.T) {
+	// Test that json tagged fields are re-encoded into strings on marshal
+	original := EmbeddedEntity{
+		Field1:  "test1",
+		Raw:     ImplicitSchema{[]interface{}{nil}},
+		Payload: EmbeddedPayload{Name: "inner", Count: 7},
+		Items:   []string{"a", "b"},
+	}
+
+	data, err := MarshalExplicitSchema(original)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+
+	expected := `["test1",null,"[[null]]","[\"inner\",7]","[\"a\",\"b\"]",null]`
+	lines := strings.Split(string(data), "\r\n")
+	if lines[1] != expected {
+		t.Errorf("Expected %s, got %s", expected, lines[1])
+	}
+
+	result, err := UnmarshalExplicitSchema[EmbeddedEntity](data, true)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if result.Payload != original.Payload {
+		t.Errorf("Expected Payload = %+v, got %+v", original.Payload, result.Payload)
+	}
+}
+
+func TestUnmarshalExplicitSchemaWithInvalidEmbeddedJSON(t *testing.T) {
+	// Test that a non-string or malformed embedded document is reported
+	testCases := []string{
+		`["test1",null,["not","a","string"]]`,
+		`["test1",null,"[invalid"]`,
+	}
+
+	for _, testCase := range testCases {
+		_, err := UnmarshalExplicitSchema[EmbeddedEntity]([]byte(testCase), false)
+		if err == nil {
+			t.Errorf("Expected error for %s, got nil", testCase)
+			continue
+		}
+		if !strings.Contains(err.Error(), "EmbeddedEntity.Raw [2]") {
+			t.Errorf("Expected error for field Raw, got %q", err.Error())
+		}
+	}
+}
+
+type StrictEntity struct {
+	Name   string         `beschema:"1"`
+	Count  int8           `beschema:"2"`
+	Ratio  float64        `beschema:"3"`
+	Active bool           `beschema:"4"`
+	Sub    SubEntity1     `beschema:"5"`
+	Items  []RepeatedItem `beschema:"6"`
+	Pair   [2]int         `beschema:"7"`
+}
+
+func TestUnmarshalExplicitSchemaLenientConversions(t *testing.T) {
+	// Test that the default mode keeps converting values with data loss
+	data := []byte(`["name",1.9,"oops",true,[],null,[1,2,3]]`)
+
+	result, err := UnmarshalExplicitSchema[StrictEntity](data, false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if result.Count != 1 {
+		t.Errorf("Expected Count = 1, got %d", result.Count)
+	}
+	if result.Ratio != 0 {
+		t.Errorf("Expected Ratio = 0, got %v", result.Ratio)
+	}
+	if result.Pair != [2]int{1, 2} {
+		t.Errorf("Expected Pair = [1 2], got %v", result.Pair)
+	}
+}
+
+func TestUnmarshalExplicitSchemaStrict(t *testing.T) {
+	// Test that strict mode accepts valid conversions
+	data := []byte(`["name","12",1.5,"true",["test1","test2"],[["item1",1]],[1,2]]`)
+
+	result, err := UnmarshalExplicitSchemaWithOptions[StrictEntity](data, false, DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchemaWithOptions failed: %v", err)
+	}
+	if result.Count != 12 || result.Ratio != 1.5 || !result.Active || result.Sub.Field2 != "test2" {
+		t.Errorf("Expected all fields to be set, got %+v", result)
+	}
+}
+
+func TestUnmarshalExplicitSchemaStrictErrors(t *testing.T) {
+	// Test that strict mode reports every lossy or ignored conversion with its path
+	testCases := []struct {
+		name  string
+		data  string
+		field string
+		index string
+	}{
+		{"number into string", `[1]`, "StrictEntity.Name", "[0]"},
+		{"fractional number into int", `[null,1.5]`, "StrictEntity.Count", "[1]"},
+		{"overflowing number into int", `[null,300]`, "StrictEntity.Count", "[1]"},
+		{"overflowing string into int", `[null,"300"]`, "StrictEntity.Count", "[1]"},
+		{"invalid string into int", `[null,"twelve"]`, "StrictEntity.Count", "[1]"},
+		{"bool into int", `[null,true]`, "StrictEntity.Count", "[1]"},
+		{"invalid string into float", `[null,null,"oops"]`, "StrictEntity.Ratio", "[2]"},
+		{"invalid string into bool", `[null,null,null,"yes"]`, "StrictEntity.Active", "[3]"},
+		{"number into bool", `[null,null,null,1]`, "StrictEntity.Active", "[3]"},
+		{"nested struct element", `[null,null,null,null,[],[["item1","many"]]]`, "StrictEntity.Items.Count", "[5][0][1]"},
+		{"extra fixed-size array elements", `[null,null,null,null,[],null,[1,2,3]]`, "StrictEntity.Pair", "[6]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := UnmarshalExplicitSchemaWithOptions[StrictEntity]([]byte(tc.data), false, DecodeOptions{Strict: true})
+
+			var typeErr *TypeMismatchError
+			if !errors.As(err, &typeErr) {
+				t.Fatalf("Expected *TypeMismatchError, got %T: %v", err, err)
+			}
+			if typeErr.Field != tc.field || typeErr.Index != tc.index {
+				t.Errorf("Expected error at %s %s, got %s %s", tc.field, tc.index, typeErr.Field, typeErr.Index)
+			}
+		})
+	}
+}
+
+func TestUnmarshalExplicitSchemaStrictNestedNonArray(t *testing.T) {
+	// Test that strict mode rejects non-array values for structs below the top level
+	data := []byte(`[null,null,null,null,[],[["item1",1],"item2"]]`)
+
+	if _, err := UnmarshalExplicitSchemaWithOptions[StrictEntity](data, false, DecodeOptions{Strict: true}); err == nil {
+		t.Fatalf("Expected error for non-array slice element, got nil")
+	}
+
+	type outer struct {
+		Inner OuterStruct `beschema:"1"`
+	}
+	lenient, err := UnmarshalExplicitSchema[outer]([]byte(`[["text","field"]]`), false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if lenient.Inner.Field != "field" {
+		t.Errorf("Expected Inner.Field = 'field', got '%s'", lenient.Inner.Field)
+	}
+
+	_, err = UnmarshalExplicitSchemaWithOptions[outer]([]byte(`[["text","field"]]`), false, DecodeOptions{Strict: true})
+	var typeErr *TypeMismatchError
+	if !errors.As(err, &typeErr) || typeErr.Field != "outer.Inner.Nested" || typeErr.Index != "[0][0]" {
+		t.Errorf("Expected error at outer.Inner.Nested [0][0], got %v", err)
+	}
+}
+
+// StructPositions holds a struct in every position a non-array value can appear in
+type StructPositions struct {
+	Sub   SubEntity1   `beschema:"1"`
+	Outer OuterStruct  `beschema:"2"`
+	Ptr   *SubEntity1  `beschema:"3"`
+	Items []SubEntity1 `beschema:"4"`
+}
+
+func TestUnmarshalExplicitSchemaNonArrayStructs(t *testing.T) {
+	// Test that null and non-array values for structs are ignored at any depth and in any
+	// container, and that non-array values are rejected in strict mode only
+	testCases := []struct {
+		name  string
+		data  string
+		index string
+	}{
+		{"top level", `["str"]`, "[0]"},
+		{"nested", `[null,["str"]]`, "[1][0]"},
+		{"pointer", `[null,null,"str"]`, "[2]"},
+		{"slice element", `[null,null,null,["str"]]`, "[3][0]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			nullData := strings.ReplaceAll(tc.data, `"str"`, "null")
+			for _, data := range []string{tc.data, nullData} {
+				result, err := UnmarshalExplicitSchema[StructPositions]([]byte(data), false)
+				if err != nil {
+					t.Fatalf("Expected %s to be ignored, got %v", data, err)
+				}
+				if result.Sub != (SubEntity1{}) || result.Outer != (OuterStruct{}) || result.Ptr != nil {
+					t.Errorf("Expected zero values for %s, got %+v", data, result)
+				}
+			}
+
+			if _, err := UnmarshalExplicitSchemaWithOptions[StructPositions]([]byte(nullData), false, DecodeOptions{Strict: true}); err != nil {
+				t.Errorf("Expected null to be accepted in strict mode, got %v", err)
+			}
+
+			_, err := UnmarshalExplicitSchemaWithOptions[StructPositions]([]byte(tc.data), false, DecodeOptions{Strict: true})
+			var typeErr *TypeMismatchError
+			if !errors.As(err, &typeErr) || typeErr.Index != tc.index {
+				t.Errorf("Expected *TypeMismatchError at %s, got %v", tc.index, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalExplicitSchemaDisallowUnknownSlots(t *testing.T) {
+	// Test that non-null values without a field are reported at any depth
+	opts := DecodeOptions{DisallowUnknownSlots: true}
+
+	if _, err := UnmarshalExplicitSchemaWithOptions[Entity]([]byte(`[["test1","test2"],null,["test5","test6"],null]`), false, opts); err != nil {
+		t.Errorf("Expected null slots to be accepted, got %v", err)
+	}
+
+	testCases := []struct {
+		name  string
+		data  string
+		field string
+		index string
+	}{
+		{"gap", `[["test1","test2"],"drift",["test5","test6"]]`, "Entity", "[1]"},
+		{"trailing", `[["test1","test2"],null,["test5","test6"],42]`, "Entity", "[3]"},
+		{"nested", `[["test1","test2",[]],null,["test5","test6"]]`, "Entity.Sub1", "[0][2]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := UnmarshalExplicitSchemaWithOptions[Entity]([]byte(tc.data), false, opts)
+
+			var slotErr *UnknownSlotError
+			if !errors.As(err, &slotErr) {
+				t.Fatalf("Expected *UnknownSlotError, got %T: %v", err, err)
+			}
+			if slotErr.Field != tc.field || slotErr.Index != tc.index {
+				t.Errorf("Expected error at %s %s, got %s %s", tc.field, tc.index, slotErr.Field, slotErr.Index)
+			}
+		})
+	}
+}
+
+type ProxyEntity struct {
+	Name  string      `beschema:"1"`
+	Sub   SubEntity1  `beschema:"3"`
+	Extra map[int]any `beschema:",extra"`
+	Items []ProxyItem `beschema:"4"`
+	Other map[int]any `beschema:"5,indexed"`
+}
+
+type ProxyItem struct {
+	Count int         `beschema:"2"`
+	Extra map[int]any `beschema:",extra"`
+}
+
+func TestUnmarshalExplicitSchemaWithExtraField(t *testing.T) {
+	// Test that elements without a field are collected by their 0-based array index
+	data := []byte(`["name",[1,"x"],["test1","test2"],[["a",1,true]],null,"tail"]`)
+
+	result, err := UnmarshalExplicitSchemaWithOptions[ProxyEntity](data, false, DecodeOptions{DisallowUnknownSlots: true})
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchemaWithOptions failed: %v", err)
+	}
+
+	if len(result.Extra) != 2 || result.Extra[5] != "tail" {
+		t.Errorf("Expected Extra = map[1:[1 x] 5:tail], got %v", result.Extra)
+	}
+	if arr, ok := result.Extra[1].([]interface{}); !ok || len(arr) != 2 {
+		t.Errorf("Expected Extra[1] = [1 x], got %v", result.Extra[1])
+	}
+	if len(result.Items) != 1 || result.Items[0].Count != 1 {
+		t.Fatalf("Expected Items[0].Count = 1, got %+v", result.Items)
+	}
+	if len(result.Items[0].Extra) != 2 || result.Items[0].Extra[0] != "a" || result.Items[0].Extra[2] != true {
+		t.Errorf("Expected Items[0].Extra = map[0:a 2:true], got %v", result.Items[0].Extra)
+	}
+	if result.Other != nil {
+		t.Errorf("Expected Other to be nil, got %v", result.Other)
+	}
+}
+
+func TestMarshalUnmarshalExplicitSchemaWithExtraField(t *testing.T) {
+	// Test that decoding and encoding a payload with unknown elements is lossless
+	jsonData := `["name",[1,"x"],["test1","test2"],[["a",1,true]],null,"tail"]`
+
+	result, err := UnmarshalExplicitSchema[ProxyEntity]([]byte(jsonData), false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	result.Name = "modified"
+
+	arr, err := structToArray(result, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("structToArray failed: %v", err)
+	}
+	encoded, err := json.Marshal(arr)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+
+	expected := `["modified",[1,"x"],["test1","test2"],[["a",1,true]],null,"tail"]`
+	if string(encoded) != expected {
+		t.Errorf("Expected %s, got %s", expected, string(encoded))
+	}
+}
+
+func TestMarshalExplicitSchemaExtraFieldPrecedence(t *testing.T) {
+	// Test that fields take precedence over collected elements at the same index
+	entity := ProxyEntity{
+		Name:  "name",
+		Extra: map[int]any{0: "ignored", 1: SubEntity1{Field1: "a", Field2: "b"}, -1: "ignored"},
+	}
+
+	arr, err := structToArray(entity, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("structToArray failed: %v", err)
+	}
+	encoded, err := json.Marshal(arr)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+
+	expected := `["name",["a","b"],["",""],null,null]`
+	if string(encoded) != expected {
+		t.Errorf("Expected %s, got %s", expected, string(encoded))
+	}
+}
+
+func TestMarshalExplicitSchemaExtraFieldLargeIndex(t *testing.T) {
+	// Test that collected elements beyond the maximum index are rejected instead of allocating their array
+	if _, err := MarshalExplicitSchema(ProxyEntity{Extra: map[int]any{1 << 62: "test1"}}); err == nil {
+		t.Errorf("Expected error for index beyond the maximum, got nil")
+	}
+	if _, err := MarshalExplicitSchema(ProxyEntity{Extra: map[int]any{maxMapIndex: "test1"}}); err != nil {
+		t.Errorf("Expected the maximum index to be accepted, got %v", err)
+	}
+}
+
+// Named scalar types, converted by their underlying kind
+type (
+	Status  int32
+	Flags   uint16
+	Score   float32
+	Label   string
+	Enabled bool
+	Blob    []byte
+)
+
+func TestDecodeValueConversions(t *testing.T) {
+	// Test every conversion from a JSON value into a scalar or byte slice field,
+	// in lenient mode and in strict mode
+	testCases := []struct {
+		typ      reflect.Type
+		data     string
+		expected any // the value stored in lenient mode
+		strict   bool
+		lenient  bool // whether lenient mode reports an error as well
+	}{
+		// Signed integers
+		{reflect.TypeOf(int8(0)), `127`, int8(127), true, false},
+		{reflect.TypeOf(int8(0)), `128`, int8(0), false, true},
+		{reflect.TypeOf(int8(0)), `-129`, int8(0), false, true},
+		{reflect.TypeOf(int8(0)), `1.9`, int8(1), false, false},
+		{reflect.TypeOf(int8(0)), `"12"`, int8(12), true, false},
+		{reflect.TypeOf(int8(0)), `"x"`, int8(0), false, false},
+		{reflect.TypeOf(int8(0)), `true`, int8(0), false, false},
+		{reflect.TypeOf(int8(0)), `[1]`, int8(0), false, false},
+		{reflect.TypeOf(int16(0)), `32767`, int16(32767), true, false},
+		{reflect.TypeOf(int32(0)), `-2147483648`, int32(-2147483648), true, false},
+		{reflect.TypeOf(int(0)), `9007199254740993`, int(9007199254740993), true, false},
+		{reflect.TypeOf(int64(0)), `"9223372036854775807"`, int64(9223372036854775807), true, false},
+		{reflect.TypeOf(int64(0)), `1e3`, int64(1000), true, false},
+		{reflect.TypeOf(Status(0)), `3`, Status(3), true, false},
+		{reflect.TypeOf(Status(0)), `"3"`, Status(3), true, false},
+
+		// Unsigned integers
+		{reflect.TypeOf(uint8(0)), `255`, uint8(255), true, false},
+		{reflect.TypeOf(uint8(0)), `256`, uint8(0), false, true},
+		{reflect.TypeOf(uint8(0)), `-1`, uint8(0), false, true},
+		{reflect.TypeOf(uint8(0)), `true`, uint8(0), false, false},
+		{reflect.TypeOf(uint16(0)), `65535`, uint16(65535), true, false},
+		{reflect.TypeOf(uint32(0)), `4294967295`, uint32(4294967295), true, false},
+		{reflect.TypeOf(uint32(0)), `4294967296`, uint32(0), false, true},
+		{reflect.TypeOf(uint(0)), `"42"`, uint(42), true, false},
+		{reflect.TypeOf(uint(0)), `"-42"`, uint(0), false, false},
+		{reflect.TypeOf(uint64(0)), `18446744073709551615`, uint64(18446744073709551615), true, false},
+		{reflect.TypeOf(uint64(0)), `18446744073709551616`, uint64(0), false, true},
+		{reflect.TypeOf(uint64(0)), `1.5`, uint64(1), false, false},
+		{reflect.TypeOf(uintptr(0)), `1`, uintptr(1), true, false},
+		{reflect.TypeOf(Flags(0)), `"7"`, Flags(7), true, false},
+
+		// Floating point numbers
+		{reflect.TypeOf(float32(0)), `1.5`, float32(1.5), true, false},
+		{reflect.TypeOf(float32(0)), `1e39`, float32(0), false, true},
+		{reflect.TypeOf(float64(0)), `"2.5"`, float64(2.5), true, false},
+		{reflect.TypeOf(float64(0)), `"x"`, float64(0), false, false},
+		{reflect.TypeOf(float64(0)), `true`, float64(0), false, false},
+		{reflect.TypeOf(float64(0)), `1e400`, float64(0), false, true},
+		{reflect.TypeOf(Score(0)), `2`, Score(2), true, false},
+
+		// Strings
+		{reflect.TypeOf(""), `"x"`, "x", true, false},
+		{reflect.TypeOf(""), `12345678901234567891`, "12345678901234567891", false, false},
+		{reflect.TypeOf(""), `true`, "true", false, false},
+		{reflect.TypeOf(Label("")), `"a"`, Label("a"), true, false},
+		{reflect.TypeOf(Label("")), `1.5`, Label("1.5"), false, false},
+
+		// Booleans
+		{reflect.TypeOf(false), `true`, true, true, false},
+		{reflect.TypeOf(false), `"false"`, false, true, false},
+		{reflect.TypeOf(false), `"yes"`, false, false, false},
+		{reflect.TypeOf(false), `1`, false, false, false},
+		{reflect.TypeOf(Enabled(false)), `true`, Enabled(true), true, false},
+
+		// Binary data
+		{reflect.TypeOf([]byte(nil)), `"aGVsbG8="`, []byte("hello"), true, false},
+		{reflect.TypeOf([]byte(nil)), `"aGVsbG8"`, []byte("hello"), true, false},
+		{reflect.TypeOf([]byte(nil)), `"_-8"`, []byte{0xff, 0xef}, true, false},
+		{reflect.TypeOf([]byte(nil)), `[1,2]`, []byte{1, 2}, true, false},
+		{reflect.TypeOf([]byte(nil)), `"!!"`, []byte(nil), false, true},
+		{reflect.TypeOf([]byte(nil)), `1`, []byte(nil), false, true},
+		{reflect.TypeOf(Blob(nil)), `"AQI="`, Blob{1, 2}, true, false},
+
+		// null leaves every field at its zero value
+		{reflect.TypeOf(int8(0)), `null`, int8(0), true, false},
+		{reflect.TypeOf(uint64(0)), `null`, uint64(0), true, false},
+		{reflect.TypeOf(""), `null`, "", true, false},
+		{reflect.TypeOf([]byte(nil)), `null`, []byte(nil), true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.typ.String()+" "+tc.data, func(t *testing.T) {
+			var value any
+			if err := unmarshalJSON([]byte(tc.data), &value); err != nil {
+				t.Fatalf("Invalid test data %s: %v", tc.data, err)
+			}
+
+			field := reflect.New(tc.typ).Elem()
+			err := decodeValue(field, value, DecodeOptions{})
+			if (err != nil) != tc.lenient {
+				t.Errorf("Expected lenient error = %v, got %v", tc.lenient, err)
+			}
+			if !reflect.DeepEqual(field.Interface(), tc.expected) {
+				t.Errorf("Expected %#v, got %#v", tc.expected, field.Interface())
+			}
+
+			field = reflect.New(tc.typ).Elem()
+			err = decodeValue(field, value, DecodeOptions{Strict: true})
+			if tc.strict && err != nil {
+				t.Errorf("Expected strict conversion to succeed, got %v", err)
+			}
+			var typeErr *TypeMismatchError
+			if !tc.strict && !errors.As(err, &typeErr) {
+				t.Errorf("Expected *TypeMismatchError in strict mode, got %T: %v", err, err)
+			}
+		})
+	}
+}
+
+func TestMarshalUnmarshalExplicitSchemaWithBinaryData(t *testing.T) {
+	// Test that byte slices are encoded as base64 strings and decoded back
+	type BinaryEntity struct {
+		Data   []byte  `beschema:"1"`
+		Blob   Blob    `beschema:"2"`
+		Digest [2]byte `beschema:"3"`
+		Empty  []byte  `beschema:"4"`
+	}
+
+	original := BinaryEntity{Data: []byte("hello"), Blob: Blob{1, 2}, Digest: [2]byte{3, 4}}
+	data, err := MarshalExplicitSchema(original)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+
+	expected := "32\r\n[\"aGVsbG8=\",\"AQI=\",[3,4],null]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+
+	result, err := UnmarshalExplicitSchema[BinaryEntity](data, true)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if !reflect.DeepEqual(result, original) {
+		t.Errorf("Expected %+v, got %+v", original, result)
+	}
+}
+
+// SearchRequest has the layout of a search RPC payload, whose browser requests only carry the set fields
+type SearchRequest struct {
+	Query    string           `beschema:"1"`
+	Cursor   string           `beschema:"2,omitempty"`
+	Limit    int              `beschema:"3,omitempty"`
+	Exact    bool             `beschema:"4,omitempty"`
+	Filter   *SearchFilter    `beschema:"5"`
+	Options  []string         `beschema:"6,omitempty"`
+	Metadata map[int]any      `beschema:",extra"`
+	Context  SearchContext    `beschema:"7,omitempty"`
+	Previous []SearchRequest  `beschema:"8,omitempty"`
+	Raw      ImplicitSchema   `beschema:"9,omitempty"`
+	Nested   *SearchContext   `beschema:"10,omitempty,json"`
+	Pairs    [][2]interface{} `beschema:"11,omitempty"`
+}
+
+type SearchFilter struct {
+	Kind  string `beschema:"1,omitempty"`
+	Level int    `beschema:"2,omitempty"`
+	Tags  []int  `beschema:"3,omitempty"`
+}
+
+type SearchContext struct {
+	Locale string `beschema:"1"`
+	Region string `beschema:"2,omitempty"`
+}
+
+func TestMarshalExplicitSchemaOmitEmpty(t *testing.T) {
+	// Test that empty fields tagged with omitempty are encoded as null
+	request := SearchRequest{Query: "x", Filter: &SearchFilter{Level: 2}}
+
+	arr, err := structToArray(request, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("structToArray failed: %v", err)
+	}
+
+	// Structs are never empty, like in encoding/json
+	expected := []interface{}{"x", nil, nil, nil, []interface{}{nil, 2, nil}, nil, []interface{}{"", nil}, nil, nil, nil, nil}
+	if !reflect.DeepEqual(arr, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, arr)
+	}
+}
+
+func TestMarshalExplicitSchemaTrimTrailingNulls(t *testing.T) {
+	// Test that the output is byte for byte the compact form written by the browser clients
+	testCases := []struct {
+		name     string
+		request  SearchRequest
+		expected string
+	}{
+		{
+			"query only",
+			SearchRequest{Query: "x"},
+			`["x",null,null,null,null,null,[""]]`,
+		},
+		{
+			"nested trailing nulls",
+			SearchRequest{Query: "x", Limit: 20, Filter: &SearchFilter{Kind: "recent"}, Context: SearchContext{Locale: "ko"}},
+			`["x",null,20,null,["recent"],null,["ko"]]`,
+		},
+		{
+			"collected elements and lists",
+			SearchRequest{
+				Query:    "x",
+				Context:  SearchContext{Locale: "ko", Region: "KR"},
+				Previous: []SearchRequest{{Query: "y", Filter: &SearchFilter{Tags: []int{1, 2}}}},
+				Raw:      ImplicitSchema{1, nil, []interface{}{nil, nil}, nil},
+				Nested:   &SearchContext{Locale: "en"},
+				Pairs:    [][2]interface{}{{"a", nil}},
+				Metadata: map[int]any{12: nil, 13: "meta", 14: nil},
+			},
+			`["x",null,null,null,null,null,["ko","KR"],[["y",null,null,null,[null,null,[1,2]],null,[""]]],[1,null,[]],"[\"en\"]",[["a"]],null,null,"meta"]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := MarshalExplicitSchemaWithOptions(tc.request, EncodeOptions{TrimTrailingNulls: true})
+			if err != nil {
+				t.Fatalf("MarshalExplicitSchemaWithOptions failed: %v", err)
+			}
+
+			expected := fmt.Sprintf("%d\r\n%s\r\n", len(tc.expected)+2, tc.expected)
+			if string(data) != expected {
+				t.Errorf("Expected %q, got %q", expected, data)
+			}
+		})
+	}
+}
+
+// OpaqueNode is a custom interface type holding an untyped sub-tree
+type OpaqueNode interface{}
+
+// OpaqueEntity types the slots it understands and keeps the rest opaque
+type OpaqueEntity struct {
+	Name    string          `beschema:"1"`
+	Any     any             `beschema:"2"`
+	Schema  ImplicitSchema  `beschema:"3"`
+	Raw     json.RawMessage `beschema:"4"`
+	Node    OpaqueNode      `beschema:"5"`
+	Payload any             `beschema:"6,json"`
+}
+
+func TestMarshalUnmarshalExplicitSchemaWithOpaqueFields(t *testing.T) {
+	// Test that interface, ImplicitSchema and json.RawMessage fields hold the raw sub-tree
+	data := []byte(`["test1",[1,"test2",[null]],[12345678901234567890,"test3"],[true,null],"test4","[[\"test5\"]]"]`)
+
+	result, err := UnmarshalExplicitSchema[OpaqueEntity](data, false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+
+	expectedAny := []interface{}{json.Number("1"), "test2", []interface{}{nil}}
+	if !reflect.DeepEqual(result.Any, expectedAny) {
+		t.Errorf("Expected Any = %v, got %v", expectedAny, result.Any)
+	}
+	expectedSchema := ImplicitSchema{json.Number("12345678901234567890"), "test3"}
+	if !reflect.DeepEqual(result.Schema, expectedSchema) {
+		t.Errorf("Expected Schema = %v, got %v", expectedSchema, result.Schema)
+	}
+	if string(result.Raw) != "[true,null]" {
+		t.Errorf("Expected Raw = '[true,null]', got '%s'", result.Raw)
+	}
+	if result.Node != "test4" {
+		t.Errorf("Expected Node = 'test4', got %v", result.Node)
+	}
+	expectedPayload := []interface{}{[]interface{}{"test5"}}
+	if !reflect.DeepEqual(result.Payload, expectedPayload) {
+		t.Errorf("Expected Payload = %v, got %v", expectedPayload, result.Payload)
+	}
+
+	// The sub-trees are written back unchanged
+	encoded, err := MarshalExplicitSchema(result)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+	expected := "97\r\n" + string(data) + "\r\n"
+	if string(encoded) != expected {
+		t.Errorf("Expected %q, got %q", expected, encoded)
+	}
+}
+
+// Shape is an interface with methods, implemented by decoded structs but by no raw value
+type Shape interface {
+	Area() float64
+}
+
+// Square is stored as [side]
+type Square struct {
+	Side float64 `beschema:"1"`
+}
+
+func (s *Square) Area() float64 {
+	return s.Side * s.Side
+}
+
+type ShapeEntity struct {
+	Name  string `beschema:"1"`
+	Shape Shape  `beschema:"2"`
+}
+
+func TestMarshalUnmarshalExplicitSchemaWithMethodInterface(t *testing.T) {
+	// Test that an interface field with methods encodes the value it holds
+	// and decodes only into a pointer it already holds
+	data, err := MarshalExplicitSchema(ShapeEntity{Name: "test1", Shape: &Square{Side: 2}})
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+	expected := "15\r\n[\"test1\",[2]]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+
+	target := ShapeEntity{Shape: &Square{}}
+	if err := arrayToStruct([]interface{}{"test1", []interface{}{json.Number("3")}}, &target, DecodeOptions{}); err != nil {
+		t.Fatalf("arrayToStruct failed: %v", err)
+	}
+	if target.Shape.Area() != 9 {
+		t.Errorf("Expected Area = 9, got %v", target.Shape.Area())
+	}
+
+	// Without a pointer to decode into, the raw value does not implement Shape
+	_, err = UnmarshalExplicitSchema[ShapeEntity](data, true)
+	var typeErr *TypeMismatchError
+	if !errors.As(err, &typeErr) || typeErr.Field != "ShapeEntity.Shape" {
+		t.Errorf("Expected *TypeMismatchError for ShapeEntity.Shape, got %v", err)
+	}
+}
+
+func TestUnmarshalExplicitSchemaWithInterfaceFields(t *testing.T) {
+	// Test that values not implementing the field interface are rejected
+	type withStringer struct {
+		Field1 fmt.Stringer `beschema:"1"`
+	}
+
+	_, err := UnmarshalExplicitSchema[withStringer]([]byte(`["test1"]`), false)
+	var typeErr *TypeMismatchError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected *TypeMismatchError, got %T: %v", err, err)
+	}
+
+	// A pointer already held by the field is decoded into
+	type withTarget struct {
+		Target any `beschema:"1"`
+		Null   any `beschema:"2"`
+	}
+	item := &RepeatedItem{}
+	target := withTarget{Target: item, Null: "test2"}
+	if err := arrayToStruct([]interface{}{[]interface{}{"item1", 2}, nil}, &target, DecodeOptions{}); err != nil {
+		t.Fatalf("arrayToStruct failed: %v", err)
+	}
+	if target.Target != item || item.Name != "item1" || item.Count != 2 {
+		t.Errorf("Expected Target = &{item1 2}, got %+v", target.Target)
+	}
+	if target.Null != nil {
+		t.Errorf("Expected Null = nil, got %v", target.Null)
+	}
+}
@@ -13,16 +13,26 @@ type ImplicitSchema []any
 
 // MarshalImplicitSchema serializes an ImplicitSchema into a formatted byte slice with size header and JSON content.
 func MarshalImplicitSchema(schema ImplicitSchema, withHeader bool) ([]byte, error) {
+	return MarshalImplicitSchemaWithOptions(schema, withHeader, EncodeOptions{})
+}
+
+// MarshalImplicitSchemaWithOptions is like MarshalImplicitSchema but encodes with the given options.
+func MarshalImplicitSchemaWithOptions(schema ImplicitSchema, withHeader bool, opts EncodeOptions) ([]byte, error) {
+	var value interface{} = schema
+	if opts.TrimTrailingNulls {
+		value = trimTrailingNulls(schema)
+	}
+
 	// Marshal slice directly to JSON
-	jsonData, err := json.Marshal(schema)
+	jsonData, err := json.Marshal(value)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal to JSON: %v", err)
+		return nil, fmt.Errorf("failed to marshal to JSON: %w", err)
 	}
 
 	result := ""
 	if withHeader {
 		// Calculate size (JSON data + \r\n)
-		size := len(jsonData) + 2
+		size := chunkSize(jsonData, opts.SizeMode)
 		// Format as "size\r\nJSON_data\r\n"
 		result = fmt.Sprintf("%d\r\n%s\r\n", size, string(jsonData))
 	} else {
@@ -38,49 +48,72 @@ func MarshalImplicitSchema(schema ImplicitSchema, withHeader bool) ([]byte, erro
 // otherwise, direct parsing occurs.
 // Returns the parsed ImplicitSchema or an error if the input data is invalid or unmarshalling fails.
 func UnmarshalImplicitSchema(data []byte, withHeader bool) (ImplicitSchema, error) {
+	return UnmarshalImplicitSchemaWithOptions(data, withHeader, DecodeOptions{})
+}
+
+// UnmarshalImplicitSchemaWithOptions is like UnmarshalImplicitSchema but validates the size header
+// in opts.SizeMode. The other options only apply to struct fields and are ignored.
+func UnmarshalImplicitSchemaWithOptions(data []byte, withHeader bool, opts DecodeOptions) (ImplicitSchema, error) {
 	if !withHeader {
 		// Handle data without header - direct JSON parsing
 		var result ImplicitSchema
-		if err := json.Unmarshal(data, &result); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		if err := unmarshalJSON(data, &result); err != nil {
+			return nil, newJSONSyntaxError("failed to unmarshal JSON", err, 0)
 		}
 		return result, nil
 	}
 
 	// Handle data with header (original behavior)
+	jsonData, offset, err := splitChunk(data, opts.SizeMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal to JSON array and return as ImplicitSchema
+	var result ImplicitSchema
+	if err := unmarshalJSON(jsonData, &result); err != nil {
+		return nil, newJSONSyntaxError("failed to unmarshal JSON", err, offset)
+	}
+
+	return result, nil
+}
+
+// splitChunk validates a chunk in the format "size\r\nJSON_data\r\n" and returns its JSON data,
+// along with the byte offset in data at which the JSON data starts.
+// The size header is validated in the given mode.
+func splitChunk(data []byte, mode SizeMode) ([]byte, int64, error) {
 	// Convert data to string
 	dataStr := string(data)
 
 	// Split by \r\n (Windows-style line breaks)
+	lineEnding := "\r\n"
 	lines := strings.Split(dataStr, "\r\n")
 	if len(lines) < 2 {
 		// Try splitting by \n only (Unix-style line breaks)
+		lineEnding = "\n"
 		lines = strings.Split(dataStr, "\n")
 		if len(lines) < 2 {
-			return nil, fmt.Errorf("invalid data format: expected at least 2 lines")
+			return nil, -1, newSyntaxError("invalid data format: expected at least 2 lines", nil)
 		}
 	}
 
 	// Parse size information from the first line
 	expectedSize, err := strconv.Atoi(strings.TrimSpace(lines[0]))
 	if err != nil {
-		return nil, fmt.Errorf("invalid size format: %v", err)
+		syntaxErr := newSyntaxError("invalid size format", err)
+		syntaxErr.Offset = 0
+		return nil, -1, syntaxErr
 	}
 
 	// Parse actual JSON data from the second line
 	jsonData := strings.TrimSpace(lines[1])
+	offset := int64(len(lines[0]) + len(lineEnding) + strings.Index(lines[1], jsonData))
 
-	// Actual data size is JSON data + \r\n (2 bytes)
-	actualSize := len(jsonData) + 2
-	if actualSize != expectedSize {
-		return nil, fmt.Errorf("data size mismatch: expected %d, got %d", expectedSize, actualSize)
+	// Actual data size is JSON data + \r\n
+	actualSize, ok := checkSize(expectedSize, []byte(jsonData), mode)
+	if !ok {
+		return nil, -1, &SizeMismatchError{Expected: expectedSize, Actual: actualSize, Chunk: -1, Offset: 0}
 	}
 
-	// Unmarshal to JSON array and return as ImplicitSchema
-	var result ImplicitSchema
-	if err := json.Unmarshal([]byte(jsonData), &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
-	}
-
-	return result, nil
+	return []byte(jsonData), offset, nil
 }
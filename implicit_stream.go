@@ -5,6 +5,9 @@ import (
 	"strings"
 )
 
+// DefaultMagicByte is the anti-XSSI prefix that precedes every batchexecute response stream.
+const DefaultMagicByte = ")]}'"
+
 // Stream represents a structured data stream containing a magic byte and multiple implicit schemas.
 type Stream struct {
 	MagicByte []byte
@@ -16,6 +19,12 @@ type Stream struct {
 // The first line represents the magic byte, and later lines contain schema data in size + JSON pair format.
 // Returns a Stream object on success or an error if the input format is invalid or schema unmarshalling fails.
 func UnmarshalImplicitStream(data []byte) (*Stream, error) {
+	return UnmarshalImplicitStreamWithOptions(data, DecodeOptions{})
+}
+
+// UnmarshalImplicitStreamWithOptions is like UnmarshalImplicitStream but validates size headers
+// in opts.SizeMode, e.g. DecodeOptions{SizeMode: SizeAuto} for captures that count characters.
+func UnmarshalImplicitStreamWithOptions(data []byte, opts DecodeOptions) (*Stream, error) {
 	// Convert data to string
 	dataStr := string(data)
 
@@ -27,13 +36,19 @@ func UnmarshalImplicitStream(data []byte) (*Stream, error) {
 		lines = strings.Split(dataStr, "\n")
 		lineEnding = "\n"
 		if len(lines) < 3 {
-			return nil, fmt.Errorf("invalid stream format: expected at least 3 lines")
+			return nil, newSyntaxError("invalid stream format: expected at least 3 lines", nil)
 		}
 	}
 
 	// Parse magic byte from the first line
 	magicByte := []byte(lines[0])
 
+	// Byte offset of each line, used to locate errors in the input
+	lineOffsets := make([]int64, len(lines))
+	for j := 1; j < len(lines); j++ {
+		lineOffsets[j] = lineOffsets[j-1] + int64(len(lines[j-1])+len(lineEnding))
+	}
+
 	// Skip magic byte and empty line, start parsing data pairs from line 2
 	var schemas []ImplicitSchema
 	i := 2 // Start after the magic byte (line 0) and empty line (line 1)
@@ -48,9 +63,9 @@ func UnmarshalImplicitStream(data []byte) (*Stream, error) {
 		// Parse size + data pair
 		if i+1 < len(lines) {
 			sizeData := fmt.Sprintf("%s%s%s%s", lines[i], lineEnding, lines[i+1], lineEnding)
-			schema, err := UnmarshalImplicitSchema([]byte(sizeData), true)
+			schema, err := UnmarshalImplicitSchemaWithOptions([]byte(sizeData), true, opts)
 			if err != nil {
-				return nil, fmt.Errorf("failed to parse schema at line %d: %v", i, err)
+				return nil, withLocation(err, len(schemas), lineOffsets[i])
 			}
 			schemas = append(schemas, schema)
 			i += 2 // Move to the next pair
@@ -71,6 +86,11 @@ func UnmarshalImplicitStream(data []byte) (*Stream, error) {
 // an empty line and appends each schema formatted as size and JSON data.
 // Returns a byte slice on success or an error if input stream is nil or schema serialization fails.
 func MarshalImplicitStream(stream *Stream) ([]byte, error) {
+	return MarshalImplicitStreamWithOptions(stream, EncodeOptions{})
+}
+
+// MarshalImplicitStreamWithOptions is like MarshalImplicitStream but encodes with the given options.
+func MarshalImplicitStreamWithOptions(stream *Stream, opts EncodeOptions) ([]byte, error) {
 	if stream == nil {
 		return nil, fmt.Errorf("stream cannot be nil")
 	}
@@ -80,9 +100,9 @@ func MarshalImplicitStream(stream *Stream) ([]byte, error) {
 
 	// Marshal each schema and append to the result
 	for _, schema := range stream.Schemas {
-		schemaData, err := MarshalImplicitSchema(schema, true)
+		schemaData, err := MarshalImplicitSchemaWithOptions(schema, true, opts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal schema: %v", err)
+			return nil, fmt.Errorf("failed to marshal schema: %w", err)
 		}
 		result += string(schemaData)
 	}
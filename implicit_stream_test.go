@@ -1,6 +1,7 @@
 package beschema
 
 import (
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
@@ -99,9 +100,9 @@ func TestParseStreamWithMultipleSchemas(t *testing.T) {
 	if schema2[0] != "data1" {
 		t.Errorf("Expected schema2[0] = 'data1', got %v", schema2[0])
 	}
-	// JSON unmarshaling converts numbers to float64
-	if schema2[1] != float64(42) {
-		t.Errorf("Expected schema2[1] = 42.0, got %v", schema2[1])
+	// Numbers are kept as json.Number
+	if schema2[1] != json.Number("42") {
+		t.Errorf("Expected schema2[1] = 42, got %v", schema2[1])
 	}
 }
 
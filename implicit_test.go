@@ -1,6 +1,8 @@
 package beschema
 
 import (
+	"encoding/json"
+	"strconv"
 	"testing"
 )
 
@@ -83,15 +85,15 @@ func TestMarshalUnmarshalImplicitSchemaRoundTrip(t *testing.T) {
 		}
 		actualValue := result[i]
 
-		// Handle JSON type conversion: numbers become float64
+		// Handle JSON type conversion: numbers become json.Number
 		switch expected := expectedValue.(type) {
 		case int:
-			if actual, ok := actualValue.(float64); ok {
-				if float64(expected) != actual {
-					t.Errorf("Expected result[%d] = %v, got %v", i, float64(expected), actual)
+			if actual, ok := actualValue.(json.Number); ok {
+				if strconv.Itoa(expected) != actual.String() {
+					t.Errorf("Expected result[%d] = %v, got %v", i, expected, actual)
 				}
 			} else {
-				t.Errorf("Expected result[%d] to be float64, got %T", i, actualValue)
+				t.Errorf("Expected result[%d] to be json.Number, got %T", i, actualValue)
 			}
 		default:
 			if actualValue != expectedValue {
@@ -0,0 +1,330 @@
+package beschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// InferStructs generates Go source for struct types with beschema tags that match the
+// shape of the sample arrays, e.g. the payloads of one RPC captured from several responses.
+// Shapes are merged across samples: slots that are sometimes null become pointer fields,
+// arrays whose elements share a shape become slice fields, and strings holding JSON arrays
+// become fields with the json option. Slots that are always null, or whose values have
+// incompatible shapes, are left out with a comment. The root type is named name and the
+// generated file declares package pkg.
+func InferStructs(pkg, name string, samples ...ImplicitSchema) ([]byte, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("no samples to infer from")
+	}
+
+	arrays := make([][]interface{}, len(samples))
+	for i, sample := range samples {
+		arrays[i] = sample
+	}
+
+	inf := &inferrer{}
+	inf.declareStruct(name, name, arrays)
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Generated by beschema-infer from %d sample(s).\n\n", len(samples))
+	fmt.Fprintf(&src, "package %s\n", pkg)
+	if inf.bigInt {
+		src.WriteString("\nimport \"math/big\"\n")
+	}
+	for _, decl := range inf.decls {
+		src.WriteString("\n")
+		src.WriteString(decl)
+	}
+
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated source: %w", err)
+	}
+
+	return formatted, nil
+}
+
+// inferrer collects the struct declarations generated for a set of samples
+type inferrer struct {
+	// decls holds the declarations in the order their types were first reached
+	decls []string
+	// bigInt is set if a field needs the math/big import
+	bigInt bool
+}
+
+// shapeKind classifies an observed JSON value
+type shapeKind int
+
+const (
+	kindNone shapeKind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindArray
+	kindMixed
+)
+
+// declareStruct declares a struct type with a field for every index observed in the arrays.
+// path is the field path of the arrays, used in the doc comment of the type.
+func (inf *inferrer) declareStruct(typeName, path string, arrays [][]interface{}) {
+	// Reserve the position, so that a type is declared before the types of its fields
+	pos := len(inf.decls)
+	inf.decls = append(inf.decls, "")
+
+	size := 0
+	for _, arr := range arrays {
+		if len(arr) > size {
+			size = len(arr)
+		}
+	}
+
+	var decl strings.Builder
+	if path == typeName {
+		fmt.Fprintf(&decl, "// %s is inferred from the sample arrays.\n", typeName)
+	} else {
+		fmt.Fprintf(&decl, "// %s is inferred from %s.\n", typeName, path)
+	}
+	fmt.Fprintf(&decl, "type %s struct {\n", typeName)
+	for i := 0; i < size; i++ {
+		var values []interface{}
+		for _, arr := range arrays {
+			if i < len(arr) {
+				values = append(values, arr[i])
+			}
+		}
+
+		fieldName := fmt.Sprintf("Field%d", i+1)
+		typ, options, note := inf.slotType(typeName+fieldName, path+"."+fieldName, values)
+		if typ == "" {
+			fmt.Fprintf(&decl, "\t// Slot %d is left out: %s\n", i+1, note)
+			continue
+		}
+		fmt.Fprintf(&decl, "\t%s %s `beschema:\"%d%s\"`\n", fieldName, typ, i+1, options)
+	}
+	decl.WriteString("}\n")
+
+	inf.decls[pos] = decl.String()
+}
+
+// slotType returns the Go type and the tag options for the values observed in a slot.
+// Nested struct types are declared as typeName. The type is empty if the slot
+// cannot be represented, in which case note explains why.
+func (inf *inferrer) slotType(typeName, path string, values []interface{}) (typ, options, note string) {
+	var nonNull []interface{}
+	for _, value := range values {
+		if value != nil {
+			nonNull = append(nonNull, value)
+		}
+	}
+	nullable := len(nonNull) < len(values)
+
+	if len(nonNull) == 0 {
+		return "", "", "always null"
+	}
+
+	// Strings holding JSON arrays are decoded with the json option
+	if parsed, ok := parseEmbeddedArrays(values); ok {
+		typ, _, note := inf.slotType(typeName, path, parsed)
+		if typ == "" {
+			return "", "", note
+		}
+		return typ, ",json", ""
+	}
+
+	switch kindOf(nonNull) {
+	case kindBool:
+		typ = "bool"
+	case kindNumber:
+		typ = numberType(nonNull)
+		if typ == "*big.Int" {
+			inf.bigInt = true
+			return typ, "", "" // Already nullable
+		}
+	case kindString:
+		typ = "string"
+	case kindArray:
+		arrays := make([][]interface{}, len(nonNull))
+		for i, value := range nonNull {
+			arrays[i] = value.([]interface{})
+		}
+
+		var elems []interface{}
+		for _, arr := range arrays {
+			elems = append(elems, arr...)
+		}
+		if len(elems) == 0 {
+			return "", "", "always empty"
+		}
+
+		if isList(arrays) {
+			elemType, _, note := inf.slotType(typeName+"Item", path+"[]", elems)
+			if elemType == "" {
+				return "", "", "elements " + note
+			}
+			return "[]" + elemType, "", ""
+		}
+
+		inf.declareStruct(typeName, path, arrays)
+		typ = typeName
+	default:
+		// Strings are the only scalar type every other scalar is converted into
+		if !hasKind(nonNull, kindArray) {
+			typ = "string"
+		} else {
+			return "", "", "mixed array and scalar values"
+		}
+	}
+
+	if nullable {
+		typ = "*" + typ
+	}
+	return typ, "", ""
+}
+
+// numberType returns the Go type that holds every number of a slot exactly: int64, else uint64,
+// else *big.Int for integers beyond both ranges such as large IDs, and float64 if any number has a fractional part.
+func numberType(values []interface{}) string {
+	fitsInt, fitsUint := true, true
+	for _, value := range values {
+		_, intErr := toInt64(value, true)
+		_, uintErr := toUint64(value, true)
+		var intOverflow, uintOverflow *overflowError
+		switch {
+		case intErr == nil && uintErr == nil:
+		case intErr == nil:
+			fitsUint = false // Negative
+		case uintErr == nil:
+			fitsInt = false
+		case errors.As(intErr, &intOverflow) && errors.As(uintErr, &uintOverflow):
+			fitsInt, fitsUint = false, false
+		default:
+			return "float64"
+		}
+	}
+
+	switch {
+	case fitsInt:
+		return "int64"
+	case fitsUint:
+		return "uint64"
+	default:
+		return "*big.Int"
+	}
+}
+
+// isList reports whether the arrays observed in a slot look like lists of values
+// sharing one shape rather than fixed layouts with a meaning per index.
+// Lists of scalars must vary in length; lists of arrays must hold compatible arrays
+// and more than one of them in at least one sample.
+func isList(arrays [][]interface{}) bool {
+	var elems []interface{}
+	varies, many := false, false
+	for _, arr := range arrays {
+		var nonNull []interface{}
+		for _, elem := range arr {
+			if elem != nil {
+				nonNull = append(nonNull, elem)
+			}
+		}
+		elems = append(elems, nonNull...)
+		varies = varies || len(arr) != len(arrays[0])
+		many = many || len(nonNull) > 1
+	}
+
+	switch kindOf(elems) {
+	case kindNone, kindBool, kindNumber, kindString:
+		return varies
+	case kindArray:
+		return many && compatibleArrays(elems)
+	default:
+		return false
+	}
+}
+
+// compatibleArrays reports whether the arrays have at most one kind of value at every index.
+func compatibleArrays(values []interface{}) bool {
+	var columns [][]interface{}
+	for _, value := range values {
+		for i, elem := range value.([]interface{}) {
+			if i >= len(columns) {
+				columns = append(columns, nil)
+			}
+			if elem != nil {
+				columns[i] = append(columns[i], elem)
+			}
+		}
+	}
+
+	for _, column := range columns {
+		if kindOf(column) == kindMixed {
+			return false
+		}
+	}
+	return true
+}
+
+// parseEmbeddedArrays parses the values of a slot if every non-null value is a string
+// holding a JSON array. Nulls are kept, so that the slot stays nullable.
+func parseEmbeddedArrays(values []interface{}) ([]interface{}, bool) {
+	parsed := make([]interface{}, len(values))
+	found := false
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		str, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		arr, ok := embeddedArray(str)
+		if !ok {
+			return nil, false
+		}
+		parsed[i] = arr
+		found = true
+	}
+	return parsed, found
+}
+
+// kindOf returns the kind shared by all values, kindNone if there are none,
+// or kindMixed if they differ.
+func kindOf(values []interface{}) shapeKind {
+	kind := kindNone
+	for _, value := range values {
+		k := valueKind(value)
+		if kind != kindNone && k != kind {
+			return kindMixed
+		}
+		kind = k
+	}
+	return kind
+}
+
+// hasKind reports whether any of the values is of the given kind.
+func hasKind(values []interface{}, kind shapeKind) bool {
+	for _, value := range values {
+		if valueKind(value) == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// valueKind classifies a single decoded JSON value.
+func valueKind(value interface{}) shapeKind {
+	switch value.(type) {
+	case bool:
+		return kindBool
+	case json.Number, float64:
+		return kindNumber
+	case string:
+		return kindString
+	case []interface{}:
+		return kindArray
+	default:
+		return kindMixed
+	}
+}
@@ -0,0 +1,261 @@
+package beschema
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInferStructs(t *testing.T) {
+	// Test that shapes are merged across samples into nested, pointer, slice and json fields
+	sample1, _ := UnmarshalImplicitSchema([]byte(`[["test1","[[1]]"],["test4",1],[["a",1],["b",2.5]],[1,2,3],null,true]`), false)
+	sample2, _ := UnmarshalImplicitSchema([]byte(`[["test2",null],null,[["c",1]],[1],null,false]`), false)
+
+	src, err := InferStructs("model", "Entity", sample1, sample2)
+	if err != nil {
+		t.Fatalf("InferStructs failed: %v", err)
+	}
+
+	expected := "// Generated by beschema-infer from 2 sample(s).\n" +
+		"\n" +
+		"package model\n" +
+		"\n" +
+		"// Entity is inferred from the sample arrays.\n" +
+		"type Entity struct {\n" +
+		"\tField1 EntityField1       `beschema:\"1\"`\n" +
+		"\tField2 *EntityField2      `beschema:\"2\"`\n" +
+		"\tField3 []EntityField3Item `beschema:\"3\"`\n" +
+		"\tField4 []int64            `beschema:\"4\"`\n" +
+		"\t// Slot 5 is left out: always null\n" +
+		"\tField6 bool `beschema:\"6\"`\n" +
+		"}\n" +
+		"\n" +
+		"// EntityField1 is inferred from Entity.Field1.\n" +
+		"type EntityField1 struct {\n" +
+		"\tField1 string              `beschema:\"1\"`\n" +
+		"\tField2 *EntityField1Field2 `beschema:\"2,json\"`\n" +
+		"}\n" +
+		"\n" +
+		"// EntityField1Field2 is inferred from Entity.Field1.Field2.\n" +
+		"type EntityField1Field2 struct {\n" +
+		"\tField1 EntityField1Field2Field1 `beschema:\"1\"`\n" +
+		"}\n" +
+		"\n" +
+		"// EntityField1Field2Field1 is inferred from Entity.Field1.Field2.Field1.\n" +
+		"type EntityField1Field2Field1 struct {\n" +
+		"\tField1 int64 `beschema:\"1\"`\n" +
+		"}\n" +
+		"\n" +
+		"// EntityField2 is inferred from Entity.Field2.\n" +
+		"type EntityField2 struct {\n" +
+		"\tField1 string `beschema:\"1\"`\n" +
+		"\tField2 int64  `beschema:\"2\"`\n" +
+		"}\n" +
+		"\n" +
+		"// EntityField3Item is inferred from Entity.Field3[].\n" +
+		"type EntityField3Item struct {\n" +
+		"\tField1 string  `beschema:\"1\"`\n" +
+		"\tField2 float64 `beschema:\"2\"`\n" +
+		"}\n"
+
+	if string(src) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, string(src))
+	}
+}
+
+// InferredEntity is the output of InferStructs for the samples of TestInferredStructsDecode
+type InferredEntity struct {
+	Field1 InferredEntityField1       `beschema:"1"`
+	Field2 *InferredEntityField2      `beschema:"2"`
+	Field3 []InferredEntityField3Item `beschema:"3"`
+}
+
+type InferredEntityField1 struct {
+	Field1 string `beschema:"1"`
+	Field2 *int64 `beschema:"2"`
+}
+
+type InferredEntityField2 struct {
+	Field1 string `beschema:"1"`
+}
+
+type InferredEntityField3Item struct {
+	Field1 string `beschema:"1"`
+	Field2 bool   `beschema:"2"`
+}
+
+func TestInferredStructsDecode(t *testing.T) {
+	// Test that the inferred structs decode every sample in strict mode
+	samples := []string{
+		`[["test1",1],["test2"],[["a",true],["b",false]]]`,
+		`[["test3",null],null,[]]`,
+	}
+
+	var schemas []ImplicitSchema
+	for _, sample := range samples {
+		schema, err := UnmarshalImplicitSchema([]byte(sample), false)
+		if err != nil {
+			t.Fatalf("UnmarshalImplicitSchema failed: %v", err)
+		}
+		schemas = append(schemas, schema)
+	}
+
+	src, err := InferStructs("beschema", "InferredEntity", schemas...)
+	if err != nil {
+		t.Fatalf("InferStructs failed: %v", err)
+	}
+	for _, field := range []string{
+		"Field2 *InferredEntityField2      `beschema:\"2\"`",
+		"Field3 []InferredEntityField3Item `beschema:\"3\"`",
+		"Field2 *int64 `beschema:\"2\"`",
+	} {
+		if !strings.Contains(string(src), field) {
+			t.Errorf("Expected generated source to contain %s, got:\n%s", field, string(src))
+		}
+	}
+
+	opts := DecodeOptions{Strict: true, DisallowUnknownSlots: true}
+	for _, sample := range samples {
+		if _, err := UnmarshalExplicitSchemaWithOptions[InferredEntity]([]byte(sample), false, opts); err != nil {
+			t.Errorf("Expected %s to decode into the inferred structs, got %v", sample, err)
+		}
+	}
+}
+
+func TestInferStructsWithoutSamples(t *testing.T) {
+	// Test that inference needs at least one sample
+	if _, err := InferStructs("model", "Entity"); err == nil {
+		t.Errorf("Expected error without samples, got nil")
+	}
+}
+
+func TestInferStructsMixedSlots(t *testing.T) {
+	// Test that mixed scalars become strings and incompatible slots are left out
+	sample1 := ImplicitSchema{"text", []interface{}{"a"}}
+	sample2 := ImplicitSchema{float64(1), "b"}
+
+	src, err := InferStructs("model", "Entity", sample1, sample2)
+	if err != nil {
+		t.Fatalf("InferStructs failed: %v", err)
+	}
+	if !strings.Contains(string(src), "Field1 string `beschema:\"1\"`") {
+		t.Errorf("Expected Field1 to be a string, got:\n%s", string(src))
+	}
+	if !strings.Contains(string(src), "// Slot 2 is left out: mixed array and scalar values") {
+		t.Errorf("Expected slot 2 to be left out, got:\n%s", string(src))
+	}
+}
+
+func TestInferStructsNumbers(t *testing.T) {
+	// Test that numbers get the narrowest type holding every value exactly
+	sample1, _ := UnmarshalImplicitSchema([]byte(`[1,9223372036854775808,-1,1,1]`), false)
+	sample2, _ := UnmarshalImplicitSchema([]byte(`[-9223372036854775808,18446744073709551615,18446744073709551616,1.5,1e3]`), false)
+
+	src, err := InferStructs("model", "Entity", sample1, sample2)
+	if err != nil {
+		t.Fatalf("InferStructs failed: %v", err)
+	}
+	for _, field := range []string{
+		"import \"math/big\"",
+		"Field1 int64    `beschema:\"1\"`",
+		"Field2 uint64   `beschema:\"2\"`",
+		"Field3 *big.Int `beschema:\"3\"`",
+		"Field4 float64  `beschema:\"4\"`",
+		"Field5 int64    `beschema:\"5\"`",
+	} {
+		if !strings.Contains(string(src), field) {
+			t.Errorf("Expected generated source to contain %s, got:\n%s", field, string(src))
+		}
+	}
+}
+
+func TestInferStructsGeneratedSourceDecodes(t *testing.T) {
+	// Test that the generated source compiles, decodes its own samples in strict mode
+	// and encodes them back unchanged
+	if testing.Short() {
+		t.Skip("builds a program with the go tool")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	samples := []string{
+		`[["test1",1],["test2"],[["a",true],["b",false]],12345678901234567890123,1.5,18446744073709551615]`,
+		`[["test3",null],null,[],null,2,0]`,
+	}
+	var schemas []ImplicitSchema
+	for _, sample := range samples {
+		schema, err := UnmarshalImplicitSchema([]byte(sample), false)
+		if err != nil {
+			t.Fatalf("UnmarshalImplicitSchema failed: %v", err)
+		}
+		schemas = append(schemas, schema)
+	}
+
+	src, err := InferStructs("main", "Entity", schemas...)
+	if err != nil {
+		t.Fatalf("InferStructs failed: %v", err)
+	}
+
+	root, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd failed: %v", err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module infertest\n\ngo 1.24\n\nrequire github.com/starpia-forge/be-schema v0.0.0\n\n" +
+			"replace github.com/starpia-forge/be-schema => " + root + "\n",
+		"entity.go": string(src),
+		"main.go": `package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/starpia-forge/be-schema"
+)
+
+func main() {
+	opts := beschema.DecodeOptions{Strict: true, DisallowUnknownSlots: true}
+	for _, sample := range os.Args[1:] {
+		entity, err := beschema.UnmarshalExplicitSchemaWithOptions[Entity]([]byte(sample), false, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		data, err := beschema.MarshalExplicitSchema(entity)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(string(data))
+	}
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+
+	cmd := exec.Command(goTool, append([]string{"run", "."}, samples...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod", "GOPROXY=off")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Generated program failed: %v\n%s\nsource:\n%s", err, output, src)
+	}
+
+	var expected strings.Builder
+	for _, sample := range samples {
+		fmt.Fprintf(&expected, "%d\r\n%s\r\n", len(sample)+2, sample)
+	}
+	if string(output) != expected.String() {
+		t.Errorf("Expected %q, got %q", expected.String(), output)
+	}
+}
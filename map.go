@@ -0,0 +1,169 @@
+package beschema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// isMapKeyKind reports whether a map key kind can be stored in an array: strings or integers.
+func isMapKeyKind(kind reflect.Kind, allowString bool) bool {
+	switch kind {
+	case reflect.String:
+		return allowString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+// encodeMap is a helper function that converts a map field to its array representation:
+// [[key, value], ...] sorted by key for the pairs option, or an array holding each value
+// at the 0-based index given by its key for the indexed option. Types with a custom
+// encoding encode themselves, and nil maps become null.
+func encodeMap(val reflect.Value, options tagOptions, opts EncodeOptions) (interface{}, error) {
+	if hasCustomCodec(val.Type()) || val.IsNil() {
+		return encodeValue(val, opts)
+	}
+
+	keys := val.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		switch keys[i].Kind() {
+		case reflect.String:
+			return keys[i].String() < keys[j].String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return keys[i].Int() < keys[j].Int()
+		default:
+			return keys[i].Uint() < keys[j].Uint()
+		}
+	})
+
+	var result []interface{}
+	if options.indexed {
+		if len(keys) > 0 {
+			last, err := mapIndex(keys[len(keys)-1])
+			if err != nil {
+				return nil, err
+			}
+			result = make([]interface{}, last+1)
+		}
+	} else {
+		result = make([]interface{}, 0, len(keys))
+	}
+
+	for _, key := range keys {
+		value, err := encodeValue(val.MapIndex(key), opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert key %v: %w", key, err)
+		}
+
+		if !options.indexed {
+			pair := []interface{}{key.Interface(), value}
+			if opts.TrimTrailingNulls {
+				pair = trimNulls(pair)
+			}
+			result = append(result, pair)
+			continue
+		}
+
+		index, err := mapIndex(key)
+		if err != nil {
+			return nil, err
+		}
+		result[index] = value
+	}
+
+	if opts.TrimTrailingNulls {
+		result = trimNulls(result)
+	}
+
+	return result, nil
+}
+
+// maxMapIndex is the largest key of a map stored by index. The array holding the map
+// is as long as its largest key, so a single large key would otherwise allocate a huge array.
+const maxMapIndex = 1 << 20
+
+// mapIndex is a helper function that returns the array index of an integer map key.
+func mapIndex(key reflect.Value) (int, error) {
+	var index uint64
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if key.Int() < 0 {
+			return 0, fmt.Errorf("negative index %d", key.Int())
+		}
+		index = uint64(key.Int())
+	default:
+		index = key.Uint()
+	}
+
+	if index > maxMapIndex {
+		return 0, fmt.Errorf("index %d exceeds the maximum of %d", index, maxMapIndex)
+	}
+	return int(index), nil
+}
+
+// decodeMap is a helper function that populates a map field from an array element
+// in the layout of its pairs or indexed option. Types with a custom decoding decode themselves. Null pairs, null keys and, for the
+// indexed option, null elements are skipped; a pair without a value stores the zero value.
+func decodeMap(field reflect.Value, value interface{}, options tagOptions, opts DecodeOptions) error {
+	if hasCustomCodec(field.Type()) {
+		return decodeValue(field, value, opts)
+	}
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	fieldType := field.Type()
+	arr, ok := value.([]interface{})
+	if !ok {
+		return newTypeMismatchError(value, fieldType, nil)
+	}
+
+	result := reflect.MakeMapWithSize(fieldType, len(arr))
+	for i, elem := range arr {
+		if elem == nil {
+			continue
+		}
+
+		key := reflect.New(fieldType.Key()).Elem()
+		var elemValue interface{}
+		if options.indexed {
+			if key.CanInt() && key.OverflowInt(int64(i)) || key.CanUint() && key.OverflowUint(uint64(i)) {
+				return withPath(newTypeMismatchError(value, fieldType, fmt.Errorf("index %d overflows %s", i, key.Type())), "", i)
+			}
+			key.Set(reflect.ValueOf(i).Convert(key.Type()))
+			elemValue = elem
+		} else {
+			pair, ok := elem.([]interface{})
+			if !ok || len(pair) == 0 || len(pair) > 2 && opts.Strict {
+				return withPath(newTypeMismatchError(elem, fieldType, errors.New("expected [key, value] pair")), "", i)
+			}
+			if pair[0] == nil {
+				continue
+			}
+			if err := decodeValue(key, pair[0], opts); err != nil {
+				return withPath(withPath(err, "", 0), "", i)
+			}
+			if len(pair) > 1 {
+				elemValue = pair[1]
+			}
+		}
+
+		mapValue := reflect.New(fieldType.Elem()).Elem()
+		if err := decodeValue(mapValue, elemValue, opts); err != nil {
+			if options.indexed {
+				return withPath(err, "", i)
+			}
+			return withPath(withPath(err, "", 1), "", i)
+		}
+		result.SetMapIndex(key, mapValue)
+	}
+
+	field.Set(result)
+	return nil
+}
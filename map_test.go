@@ -0,0 +1,189 @@
+package beschema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// MapEntity holds a key/value list and a sparse positional array
+type MapEntity struct {
+	Name     string               `beschema:"1"`
+	Labels   map[string]string    `beschema:"2,pairs"`
+	Slots    map[int]RepeatedItem `beschema:"3,indexed"`
+	Counts   map[int64]int        `beschema:"4,pairs"`
+	Flags    map[uint8]bool       `beschema:"5,indexed,omitempty"`
+	Settings map[string][]string  `beschema:"6,pairs"`
+}
+
+func TestMarshalUnmarshalExplicitSchemaWithMaps(t *testing.T) {
+	// Test that map fields round-trip as pairs and as sparse arrays
+	original := MapEntity{
+		Name:     "test1",
+		Labels:   map[string]string{"key2": "test3", "key1": "test2"},
+		Slots:    map[int]RepeatedItem{0: {Name: "item1", Count: 1}, 3: {Name: "item2", Count: 2}},
+		Counts:   map[int64]int{10: 1, -2: 2},
+		Settings: map[string][]string{"key3": {"a", "b"}},
+	}
+
+	arr, err := structToArray(original, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("structToArray failed: %v", err)
+	}
+
+	expected := []interface{}{
+		"test1",
+		[]interface{}{[]interface{}{"key1", "test2"}, []interface{}{"key2", "test3"}},
+		[]interface{}{[]interface{}{"item1", 1}, nil, nil, []interface{}{"item2", 2}},
+		[]interface{}{[]interface{}{int64(-2), 2}, []interface{}{int64(10), 1}},
+		nil,
+		[]interface{}{[]interface{}{"key3", []interface{}{"a", "b"}}},
+	}
+	if !reflect.DeepEqual(arr, expected) {
+		t.Errorf("Expected %v, got %v", expected, arr)
+	}
+
+	data, err := MarshalExplicitSchema(original)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+
+	result, err := UnmarshalExplicitSchema[MapEntity](data, true)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if !reflect.DeepEqual(result, original) {
+		t.Errorf("Expected %+v, got %+v", original, result)
+	}
+}
+
+func TestUnmarshalExplicitSchemaWithMaps(t *testing.T) {
+	// Test that pairs without a value, null pairs and null elements are handled
+	data := []byte(`["test1",[["key1"],null,["key2","test2"],[null,"test3"]],[null,["item1",1]],[["7",3]],[true,null,false]]`)
+
+	result, err := UnmarshalExplicitSchema[MapEntity](data, false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Labels, map[string]string{"key1": "", "key2": "test2"}) {
+		t.Errorf("Expected Labels = map[key1: key2:test2], got %v", result.Labels)
+	}
+	if !reflect.DeepEqual(result.Slots, map[int]RepeatedItem{1: {Name: "item1", Count: 1}}) {
+		t.Errorf("Expected Slots = map[1:{item1 1}], got %v", result.Slots)
+	}
+	if !reflect.DeepEqual(result.Counts, map[int64]int{7: 3}) {
+		t.Errorf("Expected Counts = map[7:3], got %v", result.Counts)
+	}
+	if !reflect.DeepEqual(result.Flags, map[uint8]bool{0: true, 2: false}) {
+		t.Errorf("Expected Flags = map[0:true 2:false], got %v", result.Flags)
+	}
+	if result.Settings != nil {
+		t.Errorf("Expected Settings = nil, got %v", result.Settings)
+	}
+}
+
+func TestMarshalExplicitSchemaWithMapsTrimTrailingNulls(t *testing.T) {
+	// Test that pairs and sparse arrays are trimmed like any other array
+	entity := MapEntity{
+		Name:   "test1",
+		Labels: map[string]string{},
+		Slots:  map[int]RepeatedItem{1: {Name: "item1"}},
+		Flags:  map[uint8]bool{},
+	}
+
+	data, err := MarshalExplicitSchemaWithOptions(entity, EncodeOptions{TrimTrailingNulls: true})
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchemaWithOptions failed: %v", err)
+	}
+
+	expected := "33\r\n[\"test1\",[],[null,[\"item1\",0]]]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestUnmarshalExplicitSchemaWithMapsErrors(t *testing.T) {
+	// Test that malformed pairs and elements are reported with their paths
+	testCases := []struct {
+		name  string
+		data  string
+		index string
+	}{
+		{"non-array map", `[null,"test2"]`, "[1]"},
+		{"non-array pair", `[null,["key1"]]`, "[1][0]"},
+		{"empty pair", `[null,[[]]]`, "[1][0]"},
+		{"invalid value", `[null,null,[["item1",1],"item2"]]`, "[2][1]"},
+		{"invalid pair value", `[null,null,null,[[1,"many"]]]`, "[3][0][1]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := UnmarshalExplicitSchemaWithOptions[MapEntity]([]byte(tc.data), false, DecodeOptions{Strict: true})
+
+			var typeErr *TypeMismatchError
+			if !errors.As(err, &typeErr) {
+				t.Fatalf("Expected *TypeMismatchError, got %T: %v", err, err)
+			}
+			if typeErr.Index != tc.index {
+				t.Errorf("Expected error at %s, got %s", tc.index, typeErr.Index)
+			}
+		})
+	}
+}
+
+func TestMarshalExplicitSchemaWithNegativeIndex(t *testing.T) {
+	// Test that negative keys cannot be stored by index
+	entity := MapEntity{Slots: map[int]RepeatedItem{-1: {Name: "item1"}}}
+
+	if _, err := MarshalExplicitSchema(entity); err == nil {
+		t.Errorf("Expected error for negative index, got nil")
+	}
+}
+
+func TestMarshalExplicitSchemaWithLargeIndex(t *testing.T) {
+	// Test that keys beyond the maximum index are rejected instead of allocating their array
+	type withUnsignedKeys struct {
+		Slots map[uint64]string `beschema:"1,indexed"`
+	}
+
+	if _, err := MarshalExplicitSchema(withUnsignedKeys{Slots: map[uint64]string{1 << 63: "test1"}}); err == nil {
+		t.Errorf("Expected error for index beyond int, got nil")
+	}
+	if _, err := MarshalExplicitSchema(MapEntity{Slots: map[int]RepeatedItem{maxMapIndex + 1: {Name: "item1"}}}); err == nil {
+		t.Errorf("Expected error for index beyond the maximum, got nil")
+	}
+	if _, err := MarshalExplicitSchema(MapEntity{Slots: map[int]RepeatedItem{maxMapIndex: {Name: "item1"}}}); err != nil {
+		t.Errorf("Expected the maximum index to be accepted, got %v", err)
+	}
+}
+
+func TestCachedPlanInvalidMapOptions(t *testing.T) {
+	// Test that map options on fields of the wrong type are reported
+	type pairsOnSlice struct {
+		Field1 []string `beschema:"1,pairs"`
+	}
+	type indexedByString struct {
+		Field1 map[string]int `beschema:"1,indexed"`
+	}
+	type bothOptions struct {
+		Field1 map[int]int `beschema:"1,pairs,indexed"`
+	}
+	type withJSON struct {
+		Field1 map[int]int `beschema:"1,indexed,json"`
+	}
+	type withoutOption struct {
+		Field1 map[string]int `beschema:"1"`
+	}
+	type jsonWithoutOption struct {
+		Field1 map[string]any `beschema:"1,json"`
+	}
+
+	for _, value := range []any{pairsOnSlice{}, indexedByString{}, bothOptions{}, withJSON{}, withoutOption{}, jsonWithoutOption{}} {
+		_, err := structToArray(value, EncodeOptions{})
+		var tagErr *InvalidTagError
+		if !errors.As(err, &tagErr) {
+			t.Errorf("Expected *InvalidTagError for %T, got %T: %v", value, err, err)
+		}
+	}
+}
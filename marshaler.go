@@ -0,0 +1,149 @@
+package beschema
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// BeschemaMarshaler is implemented by types that can convert themselves
+// into the value stored in their array slot, e.g. a timestamp stored as [seconds, nanos].
+// The returned value must be encodable with encoding/json.
+type BeschemaMarshaler interface {
+	MarshalBeschema() (any, error)
+}
+
+// BeschemaUnmarshaler is implemented by types that can populate themselves
+// from the decoded JSON value of their array slot ([]any, string, json.Number, bool or nil).
+type BeschemaUnmarshaler interface {
+	UnmarshalBeschema(value any) error
+}
+
+var (
+	beschemaMarshalerType   = reflect.TypeOf((*BeschemaMarshaler)(nil)).Elem()
+	beschemaUnmarshalerType = reflect.TypeOf((*BeschemaUnmarshaler)(nil)).Elem()
+	jsonMarshalerType       = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshalerType       = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType     = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// customTypes caches whether a reflect.Type, or a pointer to it, implements
+// any of the custom encoding interfaces. It is safe for concurrent use.
+var customTypes sync.Map
+
+// hasCustomCodec reports whether typ, or a pointer to it, implements any of the custom encoding interfaces.
+func hasCustomCodec(typ reflect.Type) bool {
+	if custom, ok := customTypes.Load(typ); ok {
+		return custom.(bool)
+	}
+
+	custom := false
+	for _, iface := range []reflect.Type{
+		beschemaMarshalerType, beschemaUnmarshalerType,
+		jsonMarshalerType, jsonUnmarshalerType,
+		textMarshalerType, textUnmarshalerType,
+	} {
+		if typ.Implements(iface) || reflect.PointerTo(typ).Implements(iface) {
+			custom = true
+			break
+		}
+	}
+
+	customTypes.Store(typ, custom)
+	return custom
+}
+
+// implementing returns val, or its address, as an implementation of iface.
+// The address is used for methods with pointer receivers if val is addressable.
+// Nil pointers and interfaces implement nothing, so they are left to the default encoding.
+func implementing(val reflect.Value, iface reflect.Type) (interface{}, bool) {
+	if val.Type().Implements(iface) {
+		if (val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface) && val.IsNil() {
+			return nil, false
+		}
+		return val.Interface(), true
+	}
+	if val.CanAddr() && reflect.PointerTo(val.Type()).Implements(iface) {
+		return val.Addr().Interface(), true
+	}
+	return nil, false
+}
+
+// marshalCustom converts a value with a custom encoding to its array representation.
+// BeschemaMarshaler takes precedence over json.Marshaler, which takes precedence over
+// encoding.TextMarshaler. ok is false if the value has no custom encoding.
+func marshalCustom(val reflect.Value) (value interface{}, ok bool, err error) {
+	if !hasCustomCodec(val.Type()) {
+		return nil, false, nil
+	}
+
+	if m, ok := implementing(val, beschemaMarshalerType); ok {
+		value, err := m.(BeschemaMarshaler).MarshalBeschema()
+		return value, true, err
+	}
+
+	if m, ok := implementing(val, jsonMarshalerType); ok {
+		jsonData, err := m.(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, true, err
+		}
+		// Keep the JSON data as is, so it is emitted unchanged when the array is marshaled
+		return json.RawMessage(jsonData), true, nil
+	}
+
+	if m, ok := implementing(val, textMarshalerType); ok {
+		text, err := m.(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, true, err
+		}
+		return string(text), true, nil
+	}
+
+	return nil, false, nil
+}
+
+// unmarshalCustom populates a value with a custom decoding from an array element.
+// BeschemaUnmarshaler takes precedence over json.Unmarshaler, which takes precedence over
+// encoding.TextUnmarshaler. ok is false if the value has no custom decoding.
+func unmarshalCustom(field reflect.Value, value interface{}) (ok bool, err error) {
+	if !hasCustomCodec(field.Type()) {
+		return false, nil
+	}
+
+	if u, ok := implementing(field, beschemaUnmarshalerType); ok {
+		if err := u.(BeschemaUnmarshaler).UnmarshalBeschema(value); err != nil {
+			return true, newTypeMismatchError(value, field.Type(), err)
+		}
+		return true, nil
+	}
+
+	if u, ok := implementing(field, jsonUnmarshalerType); ok {
+		jsonData, err := json.Marshal(value)
+		if err == nil {
+			err = u.(json.Unmarshaler).UnmarshalJSON(jsonData)
+		}
+		if err != nil {
+			return true, newTypeMismatchError(value, field.Type(), err)
+		}
+		return true, nil
+	}
+
+	if u, ok := implementing(field, textUnmarshalerType); ok {
+		if value == nil {
+			return true, nil // Ignore nil values
+		}
+		str, isString := value.(string)
+		if !isString {
+			return true, newTypeMismatchError(value, field.Type(), errors.New("expected string for encoding.TextUnmarshaler"))
+		}
+		if err := u.(encoding.TextUnmarshaler).UnmarshalText([]byte(str)); err != nil {
+			return true, newTypeMismatchError(value, field.Type(), err)
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
@@ -0,0 +1,198 @@
+package beschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Timestamp is stored as a [seconds, nanos] pair, like Google's protobuf timestamps
+type Timestamp struct {
+	Seconds int64
+	Nanos   int32
+}
+
+func (ts Timestamp) MarshalBeschema() (any, error) {
+	return []interface{}{ts.Seconds, ts.Nanos}, nil
+}
+
+func (ts *Timestamp) UnmarshalBeschema(value any) error {
+	pair, ok := value.([]interface{})
+	if !ok || len(pair) != 2 {
+		return fmt.Errorf("expected [seconds, nanos], got %v", value)
+	}
+	seconds, _ := pair[0].(json.Number).Int64()
+	nanos, _ := pair[1].(json.Number).Int64()
+	ts.Seconds, ts.Nanos = seconds, int32(nanos)
+	return nil
+}
+
+// Level is stored as its name through encoding.TextMarshaler
+type Level int
+
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(strings.Repeat("*", int(l))), nil
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	if strings.Trim(string(text), "*") != "" {
+		return errors.New("invalid level")
+	}
+	*l = Level(len(text))
+	return nil
+}
+
+type CustomEntity struct {
+	Created  Timestamp   `beschema:"1"`
+	Updated  *Timestamp  `beschema:"2"`
+	History  []Timestamp `beschema:"3"`
+	Level    Level       `beschema:"4"`
+	Modified time.Time   `beschema:"5"`
+	Nested   SubEntity1  `beschema:"6"`
+}
+
+func TestMarshalExplicitSchemaWithCustomMarshalers(t *testing.T) {
+	// Test that custom encodings are honored at any nesting depth
+	entity := CustomEntity{
+		Created:  Timestamp{Seconds: 1700000000, Nanos: 500},
+		History:  []Timestamp{{Seconds: 1, Nanos: 2}},
+		Level:    3,
+		Modified: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Nested:   SubEntity1{Field1: "test1", Field2: "test2"},
+	}
+
+	data, err := MarshalExplicitSchema(entity)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+
+	expected := `[[1700000000,500],null,[[1,2]],"***","2024-01-02T03:04:05Z",["test1","test2"]]`
+	lines := strings.Split(string(data), "\r\n")
+	if lines[1] != expected {
+		t.Errorf("Expected %s, got %s", expected, lines[1])
+	}
+}
+
+func TestUnmarshalExplicitSchemaWithCustomUnmarshalers(t *testing.T) {
+	// Test that custom decodings are honored at any nesting depth
+	data := []byte(`[[1700000000,500],[10,20],[[1,2],[3,4]],"**","2024-01-02T03:04:05Z",["test1","test2"]]`)
+
+	result, err := UnmarshalExplicitSchema[CustomEntity](data, false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+
+	if result.Created != (Timestamp{Seconds: 1700000000, Nanos: 500}) {
+		t.Errorf("Expected Created = {1700000000 500}, got %+v", result.Created)
+	}
+	if result.Updated == nil || *result.Updated != (Timestamp{Seconds: 10, Nanos: 20}) {
+		t.Errorf("Expected Updated = {10 20}, got %+v", result.Updated)
+	}
+	if len(result.History) != 2 || result.History[1] != (Timestamp{Seconds: 3, Nanos: 4}) {
+		t.Errorf("Expected History = [{1 2} {3 4}], got %+v", result.History)
+	}
+	if result.Level != 2 {
+		t.Errorf("Expected Level = 2, got %d", result.Level)
+	}
+	if !result.Modified.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("Expected Modified = 2024-01-02T03:04:05Z, got %v", result.Modified)
+	}
+	if result.Nested.Field1 != "test1" {
+		t.Errorf("Expected Nested.Field1 = 'test1', got '%s'", result.Nested.Field1)
+	}
+}
+
+func TestUnmarshalExplicitSchemaWithNullCustomPointer(t *testing.T) {
+	// Test that null leaves a pointer to a custom type nil without calling its decoder
+	data := []byte(`[[1,2],null]`)
+
+	result, err := UnmarshalExplicitSchema[CustomEntity](data, false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if result.Updated != nil {
+		t.Errorf("Expected nil Updated, got %+v", result.Updated)
+	}
+}
+
+func TestUnmarshalExplicitSchemaWithCustomUnmarshalerError(t *testing.T) {
+	// Test that errors from custom decodings are reported with their path and cause
+	data := []byte(`[[1,2],null,[[1,2],"oops"]]`)
+
+	_, err := UnmarshalExplicitSchema[CustomEntity](data, false)
+
+	var typeErr *TypeMismatchError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected *TypeMismatchError, got %T: %v", err, err)
+	}
+	if typeErr.Field != "CustomEntity.History" || typeErr.Index != "[2][1]" {
+		t.Errorf("Expected error at CustomEntity.History [2][1], got %s %s", typeErr.Field, typeErr.Index)
+	}
+	if typeErr.Err == nil || !strings.Contains(typeErr.Err.Error(), "expected [seconds, nanos]") {
+		t.Errorf("Expected the cause from UnmarshalBeschema, got %v", typeErr.Err)
+	}
+
+	_, err = UnmarshalExplicitSchema[CustomEntity]([]byte(`[[1,2],null,null,"*x"]`), false)
+	if !errors.As(err, &typeErr) || typeErr.Field != "CustomEntity.Level" {
+		t.Errorf("Expected error for CustomEntity.Level, got %v", err)
+	}
+}
+
+// TimestampCodec is an interface-typed field declaring the custom encoding methods
+type TimestampCodec interface {
+	MarshalBeschema() (any, error)
+}
+
+// TimestampDecoder is an interface-typed field declaring the custom decoding method
+type TimestampDecoder interface {
+	UnmarshalBeschema(value any) error
+}
+
+type InterfaceCodecEntity struct {
+	Codec   TimestampCodec   `beschema:"1"`
+	Decoder TimestampDecoder `beschema:"2"`
+	JSON    json.Marshaler   `beschema:"3"`
+}
+
+func TestMarshalExplicitSchemaWithNilInterfaceMarshalers(t *testing.T) {
+	// Test that nil interface fields declaring custom encodings are encoded as null
+	data, err := MarshalExplicitSchema(InterfaceCodecEntity{})
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+	expected := "18\r\n[null,null,null]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+
+	// Non-nil interface fields still use the encoding of the value they hold
+	data, err = MarshalExplicitSchema(InterfaceCodecEntity{Codec: Timestamp{Seconds: 1, Nanos: 2}, JSON: json.RawMessage(`"test1"`)})
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+	expected = "22\r\n[[1,2],null,\"test1\"]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestUnmarshalExplicitSchemaWithNilInterfaceUnmarshalers(t *testing.T) {
+	// Test that nil interface fields declaring custom decodings are left nil for null
+	result, err := UnmarshalExplicitSchema[InterfaceCodecEntity]([]byte(`[null,null,null]`), false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if result.Codec != nil || result.Decoder != nil || result.JSON != nil {
+		t.Errorf("Expected nil fields, got %+v", result)
+	}
+
+	// A value cannot be decoded into a nil interface without a concrete type
+	_, err = UnmarshalExplicitSchema[InterfaceCodecEntity]([]byte(`[null,[1,2]]`), false)
+	var typeErr *TypeMismatchError
+	if !errors.As(err, &typeErr) || typeErr.Field != "InterfaceCodecEntity.Decoder" {
+		t.Errorf("Expected *TypeMismatchError for InterfaceCodecEntity.Decoder, got %v", err)
+	}
+}
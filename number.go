@@ -0,0 +1,154 @@
+package beschema
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// unmarshalJSON is like json.Unmarshal, but decodes numbers into interface values as json.Number,
+// so that integers beyond 2^53 such as IDs and microsecond timestamps keep their exact digits.
+func unmarshalJSON(data []byte, v any) error {
+	// Report invalid data with the same errors as json.Unmarshal
+	if !json.Valid(data) {
+		return json.Unmarshal(data, v)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// overflowError reports a number that does not fit in the type it is decoded into.
+// It is reported in every decoding mode, so that an oversized ID never silently becomes zero.
+type overflowError struct {
+	value any
+	typ   string
+}
+
+func (e *overflowError) Error() string {
+	return fmt.Sprintf("%v overflows %s", e.value, e.typ)
+}
+
+// toInt64 converts a number, or a string holding an integer, to an int64 without rounding.
+// Numbers with a fractional part are truncated unless strict is set.
+func toInt64(value interface{}, strict bool) (int64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		intVal, err := strconv.ParseInt(string(v), 10, 64)
+		if err == nil {
+			return intVal, nil
+		}
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, &overflowError{v, "int64"}
+		}
+		// Fractions and exponents, e.g. 1.5 or 1e3
+		num, err := v.Float64()
+		if err != nil {
+			return 0, err
+		}
+		return floatToInt64(num, strict)
+	case float64:
+		return floatToInt64(v, strict)
+	case string:
+		intVal, err := strconv.ParseInt(v, 10, 64)
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, &overflowError{v, "int64"}
+		}
+		return intVal, err
+	default:
+		return 0, errors.New("expected number")
+	}
+}
+
+// toUint64 converts a number, or a string holding an integer, to a uint64 without rounding.
+// Numbers with a fractional part are truncated unless strict is set.
+func toUint64(value interface{}, strict bool) (uint64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		uintVal, err := strconv.ParseUint(string(v), 10, 64)
+		if err == nil {
+			return uintVal, nil
+		}
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, &overflowError{v, "uint64"}
+		}
+		// Negative numbers, fractions and exponents, e.g. -1, 1.5 or 1e3
+		num, err := v.Float64()
+		if err != nil {
+			return 0, err
+		}
+		return floatToUint64(num, strict)
+	case float64:
+		return floatToUint64(v, strict)
+	case string:
+		uintVal, err := strconv.ParseUint(v, 10, 64)
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, &overflowError{v, "uint64"}
+		}
+		return uintVal, err
+	default:
+		return 0, errors.New("expected number")
+	}
+}
+
+// toFloat64 converts a number, or a string holding a number, to a float64.
+func toFloat64(value interface{}) (float64, error) {
+	var num float64
+	var err error
+	switch v := value.(type) {
+	case json.Number:
+		num, err = v.Float64()
+	case float64:
+		return v, nil
+	case string:
+		num, err = strconv.ParseFloat(v, 64)
+	default:
+		return 0, errors.New("expected number")
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, &overflowError{value, "float64"}
+	}
+	return num, err
+}
+
+// floatToInt64 converts a float64 to an int64, rejecting values out of range
+// and, if strict is set, values with a fractional part.
+func floatToInt64(num float64, strict bool) (int64, error) {
+	if strict && num != math.Trunc(num) {
+		return 0, fmt.Errorf("%v has a fractional part", num)
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which is itself out of range
+	if num < math.MinInt64 || num >= math.MaxInt64 {
+		return 0, &overflowError{num, "int64"}
+	}
+	return int64(num), nil
+}
+
+// floatToUint64 converts a float64 to a uint64, rejecting values out of range
+// and, if strict is set, values with a fractional part.
+func floatToUint64(num float64, strict bool) (uint64, error) {
+	if strict && num != math.Trunc(num) {
+		return 0, fmt.Errorf("%v has a fractional part", num)
+	}
+	// float64(math.MaxUint64) rounds up to 2^64, which is itself out of range
+	if num <= -1 || num >= math.MaxUint64 {
+		return 0, &overflowError{num, "uint64"}
+	}
+	return uint64(num), nil
+}
+
+// decodeBase64 decodes binary data sent as a base64 string, in the standard or URL-safe alphabet,
+// with or without padding.
+func decodeBase64(str string) ([]byte, error) {
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if data, err := encoding.DecodeString(str); err == nil {
+			return data, nil
+		}
+	}
+	return nil, errors.New("invalid base64 data")
+}
@@ -0,0 +1,133 @@
+package beschema
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// bigNumbers holds integers that float64 cannot represent exactly
+const bigNumbers = `[9007199254740993,18446744073709551615,"123456789012345678901234567890",-9223372036854775808,123456789012345678901234567890]`
+
+// BigNumberEntity stores the integers of bigNumbers in exact field types
+type BigNumberEntity struct {
+	ID        int64    `beschema:"1"`
+	Timestamp uint64   `beschema:"2"`
+	Token     string   `beschema:"3"`
+	Min       int64    `beschema:"4"`
+	Huge      *big.Int `beschema:"5"`
+}
+
+func TestUnmarshalImplicitSchemaKeepsNumbers(t *testing.T) {
+	// Test that numbers are decoded as json.Number and re-emitted with their original digits
+	schema, err := UnmarshalImplicitSchema([]byte(bigNumbers), false)
+	if err != nil {
+		t.Fatalf("UnmarshalImplicitSchema failed: %v", err)
+	}
+
+	if schema[0] != json.Number("9007199254740993") {
+		t.Errorf("Expected json.Number 9007199254740993, got %T %v", schema[0], schema[0])
+	}
+
+	data, err := MarshalImplicitSchema(schema, false)
+	if err != nil {
+		t.Fatalf("MarshalImplicitSchema failed: %v", err)
+	}
+	if string(data) != bigNumbers+"\r\n" {
+		t.Errorf("Expected %s, got %s", bigNumbers, data)
+	}
+}
+
+func TestUnmarshalExplicitSchemaBigNumbers(t *testing.T) {
+	// Test that large integers are stored exactly in int64, uint64, string and *big.Int fields
+	entity, err := UnmarshalExplicitSchemaWithOptions[BigNumberEntity]([]byte(bigNumbers), false, DecodeOptions{Strict: true})
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchemaWithOptions failed: %v", err)
+	}
+
+	if entity.ID != 9007199254740993 {
+		t.Errorf("Expected ID = 9007199254740993, got %d", entity.ID)
+	}
+	if entity.Timestamp != 18446744073709551615 {
+		t.Errorf("Expected Timestamp = 18446744073709551615, got %d", entity.Timestamp)
+	}
+	if entity.Token != "123456789012345678901234567890" {
+		t.Errorf("Expected Token = 123456789012345678901234567890, got %s", entity.Token)
+	}
+	if entity.Min != -9223372036854775808 {
+		t.Errorf("Expected Min = -9223372036854775808, got %d", entity.Min)
+	}
+	if entity.Huge == nil || entity.Huge.String() != "123456789012345678901234567890" {
+		t.Errorf("Expected Huge = 123456789012345678901234567890, got %v", entity.Huge)
+	}
+
+	// Test that the same digits are emitted again
+	data, err := MarshalExplicitSchema(entity)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+	expected := "126\r\n" + bigNumbers + "\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
+
+func TestUnmarshalExplicitSchemaNumberIntoString(t *testing.T) {
+	// Test that numbers stored in string fields keep their exact digits in lenient mode
+	type Entity struct {
+		ID string `beschema:"1"`
+	}
+
+	entity, err := UnmarshalExplicitSchema[Entity]([]byte(`[12345678901234567891]`), false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if entity.ID != "12345678901234567891" {
+		t.Errorf("Expected ID = 12345678901234567891, got %s", entity.ID)
+	}
+}
+
+func TestUnmarshalExplicitSchemaNumberOverflow(t *testing.T) {
+	// Test that numbers out of range of their field are reported in every mode and never wrapped
+	type Entity struct {
+		Small int8   `beschema:"1"`
+		ID    int64  `beschema:"2"`
+		Count uint32 `beschema:"3"`
+	}
+
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{"int8", `[128]`},
+		{"int64", `[null,9223372036854775808]`},
+		{"negative uint", `[null,null,-1]`},
+		{"uint32", `[null,null,4294967296]`},
+		{"exponent", `[null,1e19]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, opts := range []DecodeOptions{{Strict: true}, {}} {
+				_, err := UnmarshalExplicitSchemaWithOptions[Entity]([]byte(tc.data), false, opts)
+				var mismatch *TypeMismatchError
+				if !errors.As(err, &mismatch) {
+					t.Fatalf("Expected *TypeMismatchError with %+v, got %T: %v", opts, err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	// Test that invalid data is reported like json.Unmarshal does
+	for _, data := range []string{``, `[1,`, `[1] [2]`, `[1] x`} {
+		var arr []interface{}
+		err := unmarshalJSON([]byte(data), &arr)
+		var syntaxErr *json.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected *json.SyntaxError for %q, got %T: %v", data, err, err)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package beschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Match is a value selected by a path, along with its concrete path.
+type Match struct {
+	// Path is the dot-separated index path of the value, e.g. "0.2.1"
+	Path  string
+	Value any
+}
+
+// WalkFunc is called by Walk for every value in a schema. Returning SkipArray
+// for an array skips its elements; any other error stops the walk and is returned by Walk.
+type WalkFunc func(path string, value any) error
+
+// SkipArray is returned by a WalkFunc to skip the elements of the array it was called with.
+var SkipArray = errors.New("skip this array")
+
+// pathSegment is a single step of a path: an array index or the wildcard
+type pathSegment struct {
+	index    int
+	wildcard bool
+}
+
+// Get returns the value at a dot-separated index path such as "0.2.1", or false if there is none.
+// Negative indexes count from the end, so "0.-1" is the last element of the first array.
+// Strings holding JSON arrays are always descended into when the path continues past them.
+// The empty path selects the schema itself.
+func (s ImplicitSchema) Get(path string) (any, bool) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var value any = []any(s)
+	for _, segment := range segments {
+		if segment.wildcard {
+			return nil, false
+		}
+		arr, ok := elements(value)
+		if !ok {
+			return nil, false
+		}
+		index, ok := resolveIndex(segment.index, len(arr))
+		if !ok {
+			return nil, false
+		}
+		value = arr[index]
+	}
+
+	return value, true
+}
+
+// GetString returns the string at path, or false if there is none.
+func (s ImplicitSchema) GetString(path string) (string, bool) {
+	value, _ := s.Get(path)
+	str, ok := value.(string)
+	return str, ok
+}
+
+// GetNumber returns the number at path, or false if there is none.
+// Integers beyond 2^53 are rounded; use GetInt64 to read them exactly.
+func (s ImplicitSchema) GetNumber(path string) (float64, bool) {
+	value, _ := s.Get(path)
+	switch value.(type) {
+	case json.Number, float64:
+		num, err := toFloat64(value)
+		return num, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// GetInt64 returns the integer at path exactly, or false if there is none or it does not fit in an int64.
+func (s ImplicitSchema) GetInt64(path string) (int64, bool) {
+	value, _ := s.Get(path)
+	switch value.(type) {
+	case json.Number, float64:
+		num, err := toInt64(value, true)
+		return num, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// GetBool returns the boolean at path, or false if there is none.
+func (s ImplicitSchema) GetBool(path string) (bool, bool) {
+	value, _ := s.Get(path)
+	b, ok := value.(bool)
+	return b, ok
+}
+
+// GetArray returns the array at path, or false if there is none.
+// A string holding a JSON array is returned parsed.
+func (s ImplicitSchema) GetArray(path string) (ImplicitSchema, bool) {
+	value, found := s.Get(path)
+	if !found {
+		return nil, false
+	}
+	arr, ok := elements(value)
+	return arr, ok
+}
+
+// Select returns every value matching a path in which "*" stands for any index, e.g. "0.*.3".
+// Matches are returned in index order. Strings holding JSON arrays are descended into
+// when the path continues past them.
+func (s ImplicitSchema) Select(path string) []Match {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil
+	}
+
+	matches := []Match{{Path: "", Value: []any(s)}}
+	for _, segment := range segments {
+		var next []Match
+		for _, match := range matches {
+			arr, ok := elements(match.Value)
+			if !ok {
+				continue
+			}
+			if segment.wildcard {
+				for i, elem := range arr {
+					next = append(next, Match{Path: joinPath(match.Path, i), Value: elem})
+				}
+				continue
+			}
+			if index, ok := resolveIndex(segment.index, len(arr)); ok {
+				next = append(next, Match{Path: joinPath(match.Path, index), Value: arr[index]})
+			}
+		}
+		matches = next
+	}
+
+	return matches
+}
+
+// Walk calls fn for every value in the schema, depth-first in index order, with its path.
+// If embeddedJSON is true, strings holding JSON arrays are passed to fn parsed and descended into.
+func (s ImplicitSchema) Walk(fn WalkFunc, embeddedJSON bool) error {
+	return walk("", s, fn, embeddedJSON)
+}
+
+// walk calls fn for every element of arr and descends into nested arrays.
+func walk(path string, arr []any, fn WalkFunc, embeddedJSON bool) error {
+	for i, value := range arr {
+		elemPath := joinPath(path, i)
+
+		if str, ok := value.(string); ok && embeddedJSON {
+			if parsed, ok := embeddedArray(str); ok {
+				value = parsed
+			}
+		}
+
+		err := fn(elemPath, value)
+		if errors.Is(err, SkipArray) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if nested, ok := value.([]any); ok {
+			if err := walk(elemPath, nested, fn, embeddedJSON); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parsePath splits a dot-separated path into its segments.
+func parsePath(path string) ([]pathSegment, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(path, ".")
+	segments := make([]pathSegment, len(parts))
+	for i, part := range parts {
+		if part == "*" {
+			segments[i].wildcard = true
+			continue
+		}
+		index, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path segment %q: %w", part, err)
+		}
+		segments[i].index = index
+	}
+
+	return segments, nil
+}
+
+// joinPath appends an index to a dot-separated path.
+func joinPath(path string, index int) string {
+	if path == "" {
+		return strconv.Itoa(index)
+	}
+	return path + "." + strconv.Itoa(index)
+}
+
+// resolveIndex converts a possibly negative index into an index of an array of the given length.
+func resolveIndex(index, length int) (int, bool) {
+	if index < 0 {
+		index += length
+	}
+	return index, index >= 0 && index < length
+}
+
+// elements returns the elements of an array value, parsing strings holding JSON arrays.
+func elements(value any) ([]any, bool) {
+	switch v := value.(type) {
+	case []any:
+		return v, true
+	case ImplicitSchema:
+		return v, true
+	case string:
+		return embeddedArray(v)
+	default:
+		return nil, false
+	}
+}
+
+// embeddedArray parses a string holding a JSON array, such as the payload of a wrb.fr envelope.
+func embeddedArray(str string) ([]any, bool) {
+	if !strings.HasPrefix(strings.TrimSpace(str), "[") {
+		return nil, false
+	}
+	var arr []any
+	if err := unmarshalJSON([]byte(str), &arr); err != nil {
+		return nil, false
+	}
+	return arr, true
+}
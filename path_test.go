@@ -0,0 +1,131 @@
+package beschema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// pathSchema is a chunk holding a wrb.fr envelope with an embedded JSON payload
+var pathSchema = ImplicitSchema{
+	[]interface{}{"wrb.fr", "abc123", `[["test1","test2"],null,["test5",2,true]]`, nil, nil, nil, "generic"},
+	[]interface{}{"di", float64(22)},
+}
+
+func TestImplicitSchemaGet(t *testing.T) {
+	// Test that values are reached by index paths, through embedded JSON strings
+	testCases := []struct {
+		path     string
+		expected any
+		found    bool
+	}{
+		{"0.1", "abc123", true},
+		{"1.1", float64(22), true},
+		{"0.2.0.1", "test2", true},
+		{"0.2.1", nil, true},
+		{"0.2.-1.2", true, true},
+		{"0.-1", "generic", true},
+		{"0.3.0", nil, false},
+		{"2", nil, false},
+		{"0.*", nil, false},
+		{"0.x", nil, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			value, found := pathSchema.Get(tc.path)
+			if found != tc.found || value != tc.expected {
+				t.Errorf("Expected %v (%v), got %v (%v)", tc.expected, tc.found, value, found)
+			}
+		})
+	}
+
+	if root, found := pathSchema.Get(""); !found || len(root.([]any)) != 2 {
+		t.Errorf("Expected the empty path to select the schema, got %v", root)
+	}
+}
+
+func TestImplicitSchemaTypedGetters(t *testing.T) {
+	// Test that typed getters check the type of the value
+	if str, ok := pathSchema.GetString("0.2.0.0"); !ok || str != "test1" {
+		t.Errorf("Expected GetString = test1, got %q (%v)", str, ok)
+	}
+	if _, ok := pathSchema.GetString("1.1"); ok {
+		t.Errorf("Expected GetString to fail for a number")
+	}
+	if num, ok := pathSchema.GetNumber("0.2.2.1"); !ok || num != 2 {
+		t.Errorf("Expected GetNumber = 2, got %v (%v)", num, ok)
+	}
+	if num, ok := pathSchema.GetInt64("1.1"); !ok || num != 22 {
+		t.Errorf("Expected GetInt64 = 22, got %v (%v)", num, ok)
+	}
+	if b, ok := pathSchema.GetBool("0.2.2.2"); !ok || !b {
+		t.Errorf("Expected GetBool = true, got %v (%v)", b, ok)
+	}
+	if arr, ok := pathSchema.GetArray("0.2"); !ok || len(arr) != 3 {
+		t.Errorf("Expected GetArray to return the parsed payload, got %v (%v)", arr, ok)
+	}
+	if _, ok := pathSchema.GetArray("0.0"); ok {
+		t.Errorf("Expected GetArray to fail for a plain string")
+	}
+}
+
+func TestImplicitSchemaSelect(t *testing.T) {
+	// Test that wildcards select every index, skipping values that are not arrays
+	matches := pathSchema.Select("0.2.*.0")
+
+	expected := []Match{{Path: "0.2.0.0", Value: "test1"}, {Path: "0.2.2.0", Value: "test5"}}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Errorf("Expected %v, got %v", expected, matches)
+	}
+
+	matches = pathSchema.Select("*.0")
+	if len(matches) != 2 || matches[0].Value != "wrb.fr" || matches[1].Value != "di" {
+		t.Errorf("Expected the tags of both entries, got %v", matches)
+	}
+
+	if matches := pathSchema.Select("0.x"); matches != nil {
+		t.Errorf("Expected no matches for an invalid path, got %v", matches)
+	}
+}
+
+func TestImplicitSchemaWalk(t *testing.T) {
+	// Test that Walk visits every value depth-first, optionally through embedded JSON
+	var paths []string
+	err := pathSchema.Walk(func(path string, value any) error {
+		paths = append(paths, path)
+		return nil
+	}, false)
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	if len(paths) != 11 || paths[0] != "0" || paths[1] != "0.0" || paths[10] != "1.1" {
+		t.Errorf("Expected 11 paths from 0 to 1.1, got %v", paths)
+	}
+
+	var leaves []string
+	err = pathSchema.Walk(func(path string, value any) error {
+		if path == "1" {
+			return SkipArray
+		}
+		if _, ok := value.([]any); !ok {
+			leaves = append(leaves, path)
+		}
+		return nil
+	}, true)
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{"0.0", "0.1", "0.2.0.0", "0.2.0.1", "0.2.1", "0.2.2.0", "0.2.2.1", "0.2.2.2", "0.3", "0.4", "0.5", "0.6"}
+	if !reflect.DeepEqual(leaves, expected) {
+		t.Errorf("Expected %v, got %v", expected, leaves)
+	}
+
+	stop := errors.New("stop")
+	err = pathSchema.Walk(func(path string, value any) error {
+		return stop
+	}, false)
+	if err != stop {
+		t.Errorf("Expected the error of the WalkFunc, got %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package beschema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// structPlan is the field layout of a struct type, compiled once per type
+// so that encoding and decoding do not re-parse tags and re-sort fields on every call.
+type structPlan struct {
+	// fields holds the exported fields sorted by their beschema tag values
+	fields []fieldPlan
+	// size is the length of the array representation, i.e. the maximum tag value
+	size int
+	// slots reports for each array index whether a field maps to it
+	slots []bool
+	// extra is the index path of the field tagged with the extra option, or nil if there is none
+	extra []int
+	// err is the *InvalidTagError found while compiling the layout, if any
+	err error
+}
+
+// fieldPlan holds information about a struct field and its beschema tag
+type fieldPlan struct {
+	// index is the index path of the field, through embedded structs whose fields are promoted
+	index    []int
+	name     string
+	tagValue int
+	options  tagOptions
+	// depth is the number of embedded structs the field is promoted through
+	depth int
+}
+
+// tagOptions holds the comma-separated options following the index in a beschema tag
+type tagOptions struct {
+	// json marks a field stored as a JSON document encoded in a string slot
+	json bool
+	// extra marks a map[int]any field collecting the array elements no other field maps to
+	extra bool
+	// omitempty marks a field encoded as null if it holds an empty value
+	omitempty bool
+	// pairs marks a map field stored as a list of [key, value] pairs
+	pairs bool
+	// indexed marks a map field keyed by integers stored as a sparse array, keyed by 0-based index
+	indexed bool
+}
+
+// planCache maps a reflect.Type to its *structPlan. It is safe for concurrent use.
+var planCache sync.Map
+
+// cachedPlan returns the field layout of a struct type, compiling it on first use.
+// The error is an *InvalidTagError if the beschema tags of the type are invalid; it is cached as well.
+func cachedPlan(typ reflect.Type) (*structPlan, error) {
+	if plan, ok := planCache.Load(typ); ok {
+		return plan.(*structPlan), plan.(*structPlan).err
+	}
+
+	plan, _ := planCache.LoadOrStore(typ, compilePlan(typ))
+	return plan.(*structPlan), plan.(*structPlan).err
+}
+
+// compilePlan collects the exported fields of a struct type with their beschema tags,
+// sorted by tag value. Fields tagged "-" are left out. Invalid tags, indexes used by
+// more than one field and conflicting extra fields are recorded in the err field of the plan.
+//
+// The fields of embedded structs without a beschema tag are promoted into the index space
+// of the struct, following the rules of encoding/json: a field hides a promoted field of the
+// same name at the same index from a deeper level. Any other two fields at the same index conflict.
+func compilePlan(typ reflect.Type) *structPlan {
+	plan := &structPlan{}
+
+	candidates, err := collectFields(typ, nil, 0, map[reflect.Type]bool{})
+	if err != nil {
+		plan.err = err
+		return plan
+	}
+
+	// owners maps each index to the field using it; the extra field is kept at index 0
+	owners := make(map[int]fieldPlan)
+	for _, candidate := range candidates {
+		index := candidate.tagValue
+		if candidate.options.extra {
+			index = 0
+		}
+
+		owner, ok := owners[index]
+		switch {
+		case !ok:
+			owners[index] = candidate
+		case candidate.name != owner.name || candidate.depth == owner.depth:
+			if index == 0 {
+				plan.err = newInvalidTagError(typ, candidate.name, "", fmt.Sprintf("conflicts with extra field %s", owner.name))
+			} else {
+				plan.err = newInvalidTagError(typ, candidate.name, "", fmt.Sprintf("index %d is already used by field %s", index, owner.name))
+			}
+			return plan
+		case candidate.depth < owner.depth:
+			owners[index] = candidate
+		}
+	}
+
+	for index, owner := range owners {
+		if index == 0 {
+			plan.extra = owner.index
+			continue
+		}
+		plan.fields = append(plan.fields, owner)
+
+		// Find the maximum tag value to determine array size
+		if index > plan.size {
+			plan.size = index
+		}
+	}
+
+	plan.slots = make([]bool, plan.size)
+	for _, fp := range plan.fields {
+		plan.slots[fp.tagValue-1] = true
+	}
+
+	// Sort fields by beschema tag value
+	sort.Slice(plan.fields, func(i, j int) bool {
+		return plan.fields[i].tagValue < plan.fields[j].tagValue
+	})
+
+	return plan
+}
+
+// collectFields returns the fields of a struct type and the promoted fields of its embedded
+// structs, with the index path and depth of the struct type prepended. Conflicts between
+// the fields of a single struct type are reported; conflicts between levels are left to compilePlan.
+func collectFields(typ reflect.Type, path []int, depth int, visiting map[reflect.Type]bool) ([]fieldPlan, error) {
+	// A struct embedding itself through a pointer is only expanded once
+	if visiting[typ] {
+		return nil, nil
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	var fields []fieldPlan
+	// owners maps each index to the name of the field using it
+	owners := make(map[int]string)
+	extra := ""
+	for i := 0; i < typ.NumField(); i++ {
+		fieldType := typ.Field(i)
+		index := append(append([]int(nil), path...), i)
+
+		// Parse beschema tag
+		tag, tagged := fieldType.Tag.Lookup("beschema")
+		if tag == "-" {
+			continue
+		}
+
+		// Promote the fields of embedded structs without a tag
+		if embedded, ok := promotedType(fieldType); ok && !tagged {
+			promoted, err := collectFields(embedded, index, depth+1, visiting)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, promoted...)
+			continue
+		}
+
+		// Skip unexported fields
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		tagValue := i + 1 // default to field order (1-based)
+		name, options, err := parseTag(tag)
+		if err != nil {
+			return nil, newInvalidTagError(typ, fieldType.Name, tag, err.Error())
+		}
+		if name != "" {
+			parsedTag, err := strconv.Atoi(name)
+			if err != nil {
+				return nil, newInvalidTagError(typ, fieldType.Name, tag, fmt.Sprintf("index %q is not a number", name))
+			}
+			if parsedTag < 1 {
+				return nil, newInvalidTagError(typ, fieldType.Name, tag, fmt.Sprintf("index %d is out of range, indexes start at 1", parsedTag))
+			}
+			tagValue = parsedTag
+		}
+
+		// The catch-all field has no slot of its own
+		if options.extra {
+			switch {
+			case !isExtraType(fieldType.Type):
+				return nil, newInvalidTagError(typ, fieldType.Name, tag, fmt.Sprintf("extra option requires a map[int]any field, got %s", fieldType.Type))
+			case name != "" || options.json || options.omitempty || options.pairs || options.indexed:
+				return nil, newInvalidTagError(typ, fieldType.Name, tag, "extra option cannot be combined with an index or other options")
+			case extra != "":
+				return nil, newInvalidTagError(typ, fieldType.Name, tag, fmt.Sprintf("conflicts with extra field %s", extra))
+			}
+			extra = fieldType.Name
+			fields = append(fields, fieldPlan{index: index, name: fieldType.Name, options: options, depth: depth})
+			continue
+		}
+
+		if msg := checkMapOptions(fieldType.Type, options); msg != "" {
+			return nil, newInvalidTagError(typ, fieldType.Name, tag, msg)
+		}
+
+		if owner, ok := owners[tagValue]; ok {
+			return nil, newInvalidTagError(typ, fieldType.Name, tag, fmt.Sprintf("index %d is already used by field %s", tagValue, owner))
+		}
+		owners[tagValue] = fieldType.Name
+
+		fields = append(fields, fieldPlan{
+			index:    index,
+			name:     fieldType.Name,
+			tagValue: tagValue,
+			options:  options,
+			depth:    depth,
+		})
+	}
+
+	return fields, nil
+}
+
+// promotedType returns the struct type of an embedded field whose fields can be promoted:
+// a struct, or a pointer to a struct if the field is exported so that it can be allocated.
+// Types with a custom encoding are kept as ordinary fields.
+func promotedType(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous {
+		return nil, false
+	}
+
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		if !field.IsExported() {
+			return nil, false
+		}
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || hasCustomCodec(typ) {
+		return nil, false
+	}
+
+	return typ, true
+}
+
+// fieldByIndex returns the field of a struct value at an index path. Nil embedded pointers
+// on the way are allocated if alloc is set; otherwise ok is false.
+func fieldByIndex(val reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && val.Kind() == reflect.Ptr {
+			if val.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				val.Set(reflect.New(val.Type().Elem()))
+			}
+			val = val.Elem()
+		}
+		val = val.Field(x)
+	}
+	return val, true
+}
+
+// parseTag splits a beschema tag such as "3,json" into its index part and its options.
+func parseTag(tag string) (string, tagOptions, error) {
+	name, rest, _ := strings.Cut(tag, ",")
+
+	var options tagOptions
+	for _, option := range strings.Split(rest, ",") {
+		switch strings.TrimSpace(option) {
+		case "":
+		case "json":
+			options.json = true
+		case "extra":
+			options.extra = true
+		case "omitempty":
+			options.omitempty = true
+		case "pairs":
+			options.pairs = true
+		case "indexed":
+			options.indexed = true
+		default:
+			return "", options, fmt.Errorf("unknown option %q", strings.TrimSpace(option))
+		}
+	}
+
+	return strings.TrimSpace(name), options, nil
+}
+
+// checkMapOptions returns why the map options of a field do not fit its type, or "" if they do.
+func checkMapOptions(typ reflect.Type, options tagOptions) string {
+	switch {
+	case options.pairs && options.indexed:
+		return "pairs and indexed options cannot be combined"
+	case (options.pairs || options.indexed) && options.json:
+		return "map options cannot be combined with the json option"
+	case options.pairs && (typ.Kind() != reflect.Map || !isMapKeyKind(typ.Key().Kind(), true)):
+		return fmt.Sprintf("pairs option requires a map keyed by strings or integers, got %s", typ)
+	case options.indexed && (typ.Kind() != reflect.Map || !isMapKeyKind(typ.Key().Kind(), false)):
+		return fmt.Sprintf("indexed option requires a map keyed by integers, got %s", typ)
+	case typ.Kind() == reflect.Map && !options.pairs && !options.indexed && !hasCustomCodec(typ):
+		return fmt.Sprintf("map field requires the pairs or indexed option, got %s", typ)
+	default:
+		return ""
+	}
+}
+
+// hasSlot reports whether a field maps to the given 0-based array index.
+func (p *structPlan) hasSlot(index int) bool {
+	return index >= 0 && index < len(p.slots) && p.slots[index]
+}
+
+// isExtraType reports whether a field type can hold the elements collected by the extra option.
+func isExtraType(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.Int &&
+		typ.Elem().Kind() == reflect.Interface && typ.Elem().NumMethod() == 0
+}
@@ -0,0 +1,348 @@
+package beschema
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestCachedPlan(t *testing.T) {
+	// Test that a struct type is compiled once into fields sorted by tag value
+	typ := reflect.TypeOf(EntityModified{})
+
+	plan, err := cachedPlan(typ)
+	if err != nil {
+		t.Fatalf("cachedPlan failed: %v", err)
+	}
+	if cached, _ := cachedPlan(typ); plan != cached {
+		t.Errorf("Expected the same plan to be returned for the same type")
+	}
+
+	if plan.size != 3 {
+		t.Errorf("Expected plan size 3, got %d", plan.size)
+	}
+	if len(plan.fields) != 2 || plan.fields[0].name != "Sub1" || plan.fields[1].name != "Sub2" {
+		t.Errorf("Expected fields [Sub1 Sub2], got %+v", plan.fields)
+	}
+
+	// Fields declared out of order are sorted by their tag values
+	plan, _ = cachedPlan(reflect.TypeOf(TestStruct{}))
+	if plan.fields[0].name != "Field1" || plan.fields[0].index[0] != 1 {
+		t.Errorf("Expected Field1 at struct index 1 to come first, got %+v", plan.fields[0])
+	}
+}
+
+func TestCachedPlanSkipsUnexportedFields(t *testing.T) {
+	// Test that unexported fields are not part of the plan
+	type withUnexported struct {
+		Field1 string `beschema:"1"`
+		field2 string `beschema:"2"`
+	}
+	_ = withUnexported{}.field2
+
+	plan, _ := cachedPlan(reflect.TypeOf(withUnexported{}))
+	if len(plan.fields) != 1 || plan.size != 1 {
+		t.Errorf("Expected only Field1 in the plan, got %+v", plan)
+	}
+}
+
+func TestCachedPlanIgnoreTag(t *testing.T) {
+	// Test that fields tagged "-" are neither encoded nor decoded
+	type withIgnored struct {
+		Field1  string `beschema:"1"`
+		Ignored string `beschema:"-"`
+		Field3  string
+	}
+
+	data, err := MarshalExplicitSchema(withIgnored{Field1: "test1", Ignored: "test2", Field3: "test3"})
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+	expected := "24\r\n[\"test1\",null,\"test3\"]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+
+	result, err := UnmarshalExplicitSchema[withIgnored]([]byte(`["test1","test2","test3"]`), false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if result.Ignored != "" || result.Field3 != "test3" {
+		t.Errorf("Expected only Field1 and Field3 to be set, got %+v", result)
+	}
+}
+
+func TestCachedPlanInvalidTags(t *testing.T) {
+	// Test that invalid tags are reported when a type is first encoded or decoded
+	type notANumber struct {
+		Field1 string `beschema:"abc"`
+	}
+	type zeroIndex struct {
+		Field1 string `beschema:"0"`
+	}
+	type negativeIndex struct {
+		Field1 string `beschema:"-1"`
+	}
+	type unknownOption struct {
+		Field1 string `beschema:"1,jsn"`
+	}
+	type duplicateIndex struct {
+		Field1 string `beschema:"2"`
+		Field2 string `beschema:"2"`
+	}
+	type conflictingDefault struct {
+		Field1 string `beschema:"2"`
+		Field2 string
+	}
+	type extraNotMap struct {
+		Field1 string `beschema:"1"`
+		Extra  []any  `beschema:",extra"`
+	}
+	type extraWithIndex struct {
+		Field1 string      `beschema:"1"`
+		Extra  map[int]any `beschema:"2,extra"`
+	}
+	type twoExtras struct {
+		Extra1 map[int]any `beschema:",extra"`
+		Extra2 map[int]any `beschema:",extra"`
+	}
+	type nested struct {
+		Field1 duplicateIndex `beschema:"1"`
+	}
+	type other struct {
+		Code string `beschema:"1"`
+	}
+	type embeddedConflict struct {
+		PromotedBase
+		other
+	}
+	type hiddenByOtherName struct {
+		PromotedBase
+		Title string `beschema:"2"`
+	}
+
+	testCases := []struct {
+		name  string
+		value any
+		typ   reflect.Type
+		field string
+		msg   string
+	}{
+		{"not a number", notANumber{}, reflect.TypeOf(notANumber{}), "Field1", `index "abc" is not a number`},
+		{"zero index", zeroIndex{}, reflect.TypeOf(zeroIndex{}), "Field1", "index 0 is out of range, indexes start at 1"},
+		{"negative index", negativeIndex{}, reflect.TypeOf(negativeIndex{}), "Field1", "index -1 is out of range, indexes start at 1"},
+		{"unknown option", unknownOption{}, reflect.TypeOf(unknownOption{}), "Field1", `unknown option "jsn"`},
+		{"duplicate index", duplicateIndex{}, reflect.TypeOf(duplicateIndex{}), "Field2", "index 2 is already used by field Field1"},
+		{"conflicting default index", conflictingDefault{}, reflect.TypeOf(conflictingDefault{}), "Field2", "index 2 is already used by field Field1"},
+		{"extra field not a map", extraNotMap{}, reflect.TypeOf(extraNotMap{}), "Extra", "extra option requires a map[int]any field, got []interface {}"},
+		{"extra field with index", extraWithIndex{}, reflect.TypeOf(extraWithIndex{}), "Extra", "extra option cannot be combined with an index or other options"},
+		{"two extra fields", twoExtras{}, reflect.TypeOf(twoExtras{}), "Extra2", "conflicts with extra field Extra1"},
+		{"nested struct", nested{}, reflect.TypeOf(duplicateIndex{}), "Field2", "index 2 is already used by field Field1"},
+		{"embedded structs", embeddedConflict{}, reflect.TypeOf(embeddedConflict{}), "Code", "index 1 is already used by field ID"},
+		{"embedded field of another name", hiddenByOtherName{}, reflect.TypeOf(hiddenByOtherName{}), "Title", "index 2 is already used by field Name"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, encodeErr := structToArray(tc.value, EncodeOptions{})
+			target := reflect.New(reflect.TypeOf(tc.value)).Interface()
+			decodeErr := arrayToStruct([]interface{}{[]interface{}{}}, target, DecodeOptions{})
+
+			for _, err := range []error{encodeErr, decodeErr} {
+				var tagErr *InvalidTagError
+				if !errors.As(err, &tagErr) {
+					t.Fatalf("Expected *InvalidTagError, got %T: %v", err, err)
+				}
+				if tagErr.Type != tc.typ || tagErr.Field != tc.field || tagErr.Msg != tc.msg {
+					t.Errorf("Expected %s.%s: %s, got %s.%s: %s", tc.typ, tc.field, tc.msg, tagErr.Type, tagErr.Field, tagErr.Msg)
+				}
+			}
+		})
+	}
+}
+
+// PromotedBase holds the leading slots shared by several entities
+type PromotedBase struct {
+	ID   string `beschema:"1"`
+	Name string `beschema:"2"`
+}
+
+// PromotedMeta holds optional trailing slots
+type PromotedMeta struct {
+	Created int64 `beschema:"4"`
+	Updated int64 `beschema:"5"`
+}
+
+// PromotedEntity promotes the fields of its embedded structs into its own slots
+type PromotedEntity struct {
+	PromotedBase
+	*PromotedMeta
+	Name  string `beschema:"2"` // Hides PromotedBase.Name
+	Count int    `beschema:"3"`
+}
+
+func TestCachedPlanEmbeddedStructs(t *testing.T) {
+	// Test that the fields of embedded structs are promoted, with shallower fields taking precedence
+	entity := PromotedEntity{
+		PromotedBase: PromotedBase{ID: "test1", Name: "test2"},
+		PromotedMeta: &PromotedMeta{Created: 1, Updated: 2},
+		Name:         "test3",
+		Count:        5,
+	}
+
+	data, err := MarshalExplicitSchema(entity)
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+	expected := "25\r\n[\"test1\",\"test3\",5,1,2]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+
+	result, err := UnmarshalExplicitSchema[PromotedEntity](data, true)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	entity.PromotedBase.Name = ""
+	if !reflect.DeepEqual(result, entity) {
+		t.Errorf("Expected %+v, got %+v", entity, result)
+	}
+}
+
+func TestCachedPlanNilEmbeddedPointer(t *testing.T) {
+	// Test that a nil embedded pointer is encoded as nulls and only allocated for non-null values
+	data, err := MarshalExplicitSchema(PromotedEntity{PromotedBase: PromotedBase{ID: "test1"}})
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchema failed: %v", err)
+	}
+	expected := "26\r\n[\"test1\",\"\",0,null,null]\r\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+
+	result, err := UnmarshalExplicitSchema[PromotedEntity]([]byte(`["test1","test2",5,null,null]`), false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if result.PromotedMeta != nil {
+		t.Errorf("Expected PromotedMeta = nil, got %+v", result.PromotedMeta)
+	}
+
+	result, err = UnmarshalExplicitSchema[PromotedEntity]([]byte(`["test1","test2",5,null,2]`), false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if result.PromotedMeta == nil || result.Updated != 2 {
+		t.Errorf("Expected PromotedMeta.Updated = 2, got %+v", result.PromotedMeta)
+	}
+}
+
+func TestCachedPlanTaggedEmbeddedStruct(t *testing.T) {
+	// Test that embedded structs with a tag stay nested
+	type withTaggedEmbedded struct {
+		PromotedBase `beschema:"1"`
+		Count        int `beschema:"2"`
+	}
+
+	arr, err := structToArray(withTaggedEmbedded{PromotedBase: PromotedBase{ID: "test1", Name: "test2"}, Count: 3}, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("structToArray failed: %v", err)
+	}
+	expected := []interface{}{[]interface{}{"test1", "test2"}, 3}
+	if !reflect.DeepEqual(arr, expected) {
+		t.Errorf("Expected %v, got %v", expected, arr)
+	}
+}
+
+func TestCachedPlanConcurrent(t *testing.T) {
+	// Test that concurrent encoding and decoding of the same type is safe
+	data := []byte("[[\"test1\",\"test2\"],null,[\"test5\",2,\"test6\",3]]")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				result, err := UnmarshalExplicitSchema[EntityModified](data, false)
+				if err != nil {
+					t.Errorf("UnmarshalExplicitSchema failed: %v", err)
+					return
+				}
+				if result.Sub2.Field1 != "test6" {
+					t.Errorf("Expected Sub2.Field1 = 'test6', got '%s'", result.Sub2.Field1)
+					return
+				}
+				if _, err := MarshalExplicitSchema(result); err != nil {
+					t.Errorf("MarshalExplicitSchema failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// benchmarkEntity is a payload with nested structs and slices, typical of batchexecute responses
+var benchmarkEntity = RepeatedEntity{
+	Tags:   []string{"a", "b", "c"},
+	Items:  []RepeatedItem{{Name: "item1", Count: 1}, {Name: "item2", Count: 2}, {Name: "item3", Count: 3}},
+	Matrix: [][]int{{1, 2}, {3}},
+	Pair:   [2]float64{1.5, 2.5},
+}
+
+// uncachePlans removes the plans of the benchmark types, so the next call compiles them again
+func uncachePlans() {
+	planCache.Delete(reflect.TypeOf(RepeatedEntity{}))
+	planCache.Delete(reflect.TypeOf(RepeatedItem{}))
+}
+
+func BenchmarkStructToArray(b *testing.B) {
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := structToArray(benchmarkEntity, EncodeOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			uncachePlans()
+			if _, err := structToArray(benchmarkEntity, EncodeOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkArrayToStruct(b *testing.B) {
+	arr, err := structToArray(benchmarkEntity, EncodeOptions{})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var result RepeatedEntity
+			if err := arrayToStruct(arr, &result, DecodeOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			uncachePlans()
+			var result RepeatedEntity
+			if err := arrayToStruct(arr, &result, DecodeOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package beschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// RPCCall represents a single RPC call within a batched batchexecute request.
+type RPCCall struct {
+	// RPCID is the id of the RPC to call (e.g. "abc123")
+	RPCID string
+	// Payload is a struct with beschema tags, an ImplicitSchema, or nil
+	Payload any
+	// Index identifies the call in the response; see BatchRequest.FReq for the default
+	Index string
+}
+
+// BatchRequest represents a batchexecute request made of one or more RPC calls.
+type BatchRequest struct {
+	Calls []RPCCall
+	// At is the optional XSRF token sent as the "at" form value
+	At string
+	// ReqID is the optional "_reqid" query parameter; it is omitted when zero
+	ReqID int
+	// Options controls how payloads are encoded, e.g. EncodeOptions{TrimTrailingNulls: true}
+	// to match the requests of Google's own clients. SizeMode does not apply.
+	Options EncodeOptions
+}
+
+// NewBatchRequest creates a BatchRequest for the given calls.
+func NewBatchRequest(calls ...RPCCall) *BatchRequest {
+	return &BatchRequest{Calls: calls}
+}
+
+// FReq builds the f.req form value in the format:
+// [[["rpcId","<json-string payload>",null,"generic"],...]].
+// Calls without an index get DefaultIndex if they are the only call,
+// or their 1-based position otherwise.
+func (r *BatchRequest) FReq() (string, error) {
+	if len(r.Calls) == 0 {
+		return "", fmt.Errorf("batch request has no calls")
+	}
+
+	entries := make([]interface{}, len(r.Calls))
+	for i, call := range r.Calls {
+		payload, err := marshalPayload(call.Payload, r.Options)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal payload of %s: %w", call.RPCID, err)
+		}
+
+		index := call.Index
+		if index == "" {
+			if len(r.Calls) == 1 {
+				index = DefaultIndex
+			} else {
+				index = strconv.Itoa(i + 1)
+			}
+		}
+
+		entries[i] = []interface{}{call.RPCID, payload, nil, index}
+	}
+
+	fReq, err := marshalJSON([]interface{}{entries})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal to JSON: %w", err)
+	}
+
+	return string(fReq), nil
+}
+
+// Body builds the URL-encoded request body holding the f.req value and, if set, the at token.
+func (r *BatchRequest) Body() (string, error) {
+	fReq, err := r.FReq()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("f.req", fReq)
+	if r.At != "" {
+		form.Set("at", r.At)
+	}
+
+	return form.Encode(), nil
+}
+
+// Query builds the rpcids, _reqid and rt=c query parameters of the request URL.
+// RPC ids are listed once each, in order of first appearance.
+func (r *BatchRequest) Query() url.Values {
+	var rpcIDs []string
+	seen := make(map[string]bool)
+	for _, call := range r.Calls {
+		if seen[call.RPCID] {
+			continue
+		}
+		seen[call.RPCID] = true
+		rpcIDs = append(rpcIDs, call.RPCID)
+	}
+
+	query := url.Values{}
+	query.Set("rpcids", strings.Join(rpcIDs, ","))
+	if r.ReqID != 0 {
+		query.Set("_reqid", strconv.Itoa(r.ReqID))
+	}
+	query.Set("rt", "c")
+
+	return query
+}
+
+// marshalPayload encodes an RPC payload into the JSON string carried inside f.req.
+// Structs are converted through the explicit schema path.
+func marshalPayload(payload any, opts EncodeOptions) (string, error) {
+	var value interface{}
+	if schema, ok := payload.(ImplicitSchema); ok {
+		value = schema
+		if opts.TrimTrailingNulls {
+			value = trimTrailingNulls(schema)
+		}
+	} else if payload != nil {
+		encoded, err := encodeValue(reflect.ValueOf(payload), opts)
+		if err != nil {
+			return "", err
+		}
+		value = encoded
+	}
+
+	jsonData, err := marshalJSON(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonData), nil
+}
+
+// marshalJSON is like json.Marshal, but leaves &, < and > unescaped like JSON.stringify
+// in the browser does, so that payloads holding URLs match captured requests.
+func marshalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,136 @@
+package beschema
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBatchRequestFReqSingleCall(t *testing.T) {
+	// Test that a single call gets the generic index and a JSON-string payload
+	request := NewBatchRequest(RPCCall{
+		RPCID:   "abc123",
+		Payload: SubEntity1{Field1: "test1", Field2: "test2"},
+	})
+
+	fReq, err := request.FReq()
+	if err != nil {
+		t.Fatalf("FReq failed: %v", err)
+	}
+
+	expected := `[[["abc123","[\"test1\",\"test2\"]",null,"generic"]]]`
+	if fReq != expected {
+		t.Errorf("Expected %s, got %s", expected, fReq)
+	}
+}
+
+func TestBatchRequestFReqMultipleCalls(t *testing.T) {
+	// Test that multiple calls are numbered and implicit or empty payloads are supported
+	request := NewBatchRequest(
+		RPCCall{RPCID: "abc123", Payload: ImplicitSchema{nil, "test", 1}},
+		RPCCall{RPCID: "def456", Payload: &SubEntity2{Field1: "test5"}},
+		RPCCall{RPCID: "ghi789", Index: "custom"},
+	)
+
+	fReq, err := request.FReq()
+	if err != nil {
+		t.Fatalf("FReq failed: %v", err)
+	}
+
+	expected := `[[["abc123","[null,\"test\",1]",null,"1"],["def456","[\"test5\",\"\"]",null,"2"],["ghi789","null",null,"custom"]]]`
+	if fReq != expected {
+		t.Errorf("Expected %s, got %s", expected, fReq)
+	}
+}
+
+func TestBatchRequestFReqWithoutCalls(t *testing.T) {
+	// Test that an empty request is rejected
+	if _, err := NewBatchRequest().FReq(); err == nil {
+		t.Errorf("Expected error for request without calls, got nil")
+	}
+}
+
+func TestBatchRequestBodyAndQuery(t *testing.T) {
+	// Test building the URL-encoded body and the query parameters
+	request := NewBatchRequest(
+		RPCCall{RPCID: "abc123", Payload: ImplicitSchema{"test"}},
+		RPCCall{RPCID: "abc123", Payload: ImplicitSchema{"test2"}},
+		RPCCall{RPCID: "def456"},
+	)
+	request.At = "token:123"
+	request.ReqID = 100001
+
+	body, err := request.Body()
+	if err != nil {
+		t.Fatalf("Body failed: %v", err)
+	}
+
+	form, err := url.ParseQuery(body)
+	if err != nil {
+		t.Fatalf("Failed to parse body: %v", err)
+	}
+	fReq, _ := request.FReq()
+	if form.Get("f.req") != fReq {
+		t.Errorf("Expected f.req %s, got %s", fReq, form.Get("f.req"))
+	}
+	if form.Get("at") != "token:123" {
+		t.Errorf("Expected at = 'token:123', got %q", form.Get("at"))
+	}
+
+	query := request.Query()
+	expected := "_reqid=100001&rpcids=abc123%2Cdef456&rt=c"
+	if query.Encode() != expected {
+		t.Errorf("Expected query %s, got %s", expected, query.Encode())
+	}
+}
+
+func TestBatchRequestFReqWithoutHTMLEscaping(t *testing.T) {
+	// Test that &, < and > are sent literally, like JSON.stringify in the browser
+	request := NewBatchRequest(RPCCall{RPCID: "abc123", Payload: ImplicitSchema{"https://x?a=1&b=<2>"}})
+
+	fReq, err := request.FReq()
+	if err != nil {
+		t.Fatalf("FReq failed: %v", err)
+	}
+
+	expected := `[[["abc123","[\"https://x?a=1&b=<2>\"]",null,"generic"]]]`
+	if fReq != expected {
+		t.Errorf("Expected %s, got %s", expected, fReq)
+	}
+}
+
+func TestBatchRequestPayloadRoundTrip(t *testing.T) {
+	// Test that a request payload can be read back with the explicit schema path
+	original := SubEntity1{Field1: "test1", Field2: "test2"}
+	payload, err := marshalPayload(original, EncodeOptions{})
+	if err != nil {
+		t.Fatalf("marshalPayload failed: %v", err)
+	}
+
+	result, err := UnmarshalExplicitSchema[SubEntity1]([]byte(payload), false)
+	if err != nil {
+		t.Fatalf("UnmarshalExplicitSchema failed: %v", err)
+	}
+	if result != original {
+		t.Errorf("Expected %+v, got %+v", original, result)
+	}
+}
+
+func TestBatchRequestFReqTrimTrailingNulls(t *testing.T) {
+	// Test that trimmed payloads match a request written by the browser client byte for byte,
+	// while the envelope of each call keeps its null slot
+	request := NewBatchRequest(
+		RPCCall{RPCID: "abc123", Payload: SearchRequest{Query: "test1", Limit: 20, Filter: &SearchFilter{Kind: "recent"}, Context: SearchContext{Locale: "ko"}}},
+		RPCCall{RPCID: "def456", Payload: ImplicitSchema{"test2", nil, []interface{}{nil, 1, nil}, nil}},
+	)
+	request.Options = EncodeOptions{TrimTrailingNulls: true}
+
+	fReq, err := request.FReq()
+	if err != nil {
+		t.Fatalf("FReq failed: %v", err)
+	}
+
+	expected := `[[["abc123","[\"test1\",null,20,null,[\"recent\"],null,[\"ko\"]]",null,"1"],["def456","[\"test2\",null,[null,1]]",null,"2"]]]`
+	if fReq != expected {
+		t.Errorf("Expected %s, got %s", expected, fReq)
+	}
+}
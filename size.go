@@ -0,0 +1,79 @@
+package beschema
+
+import (
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// SizeMode selects the unit in which the size header of a chunk counts its JSON data.
+// In every mode the trailing "\r\n" counts as 2.
+type SizeMode int
+
+const (
+	// SizeBytes counts UTF-8 bytes. It is the default.
+	SizeBytes SizeMode = iota
+	// SizeRunes counts Unicode code points.
+	SizeRunes
+	// SizeUTF16 counts UTF-16 code units, like the length of a JavaScript string.
+	SizeUTF16
+	// SizeAuto accepts a size header in any of the units above.
+	// It only applies to decoding; encoding counts bytes.
+	SizeAuto
+	// SizeLenient accepts any size header without validating it.
+	// It only applies to decoding; encoding counts bytes.
+	SizeLenient
+)
+
+// String returns the name of the size mode.
+func (m SizeMode) String() string {
+	switch m {
+	case SizeBytes:
+		return "bytes"
+	case SizeRunes:
+		return "runes"
+	case SizeUTF16:
+		return "utf16"
+	case SizeAuto:
+		return "auto"
+	case SizeLenient:
+		return "lenient"
+	default:
+		return fmt.Sprintf("SizeMode(%d)", int(m))
+	}
+}
+
+// chunkSize returns the size header of JSON data counted in the given mode.
+func chunkSize(jsonData []byte, mode SizeMode) int {
+	switch mode {
+	case SizeRunes:
+		return utf8.RuneCount(jsonData) + 2
+	case SizeUTF16:
+		size := 0
+		for _, r := range string(jsonData) {
+			size += utf16.RuneLen(r)
+		}
+		return size + 2
+	default:
+		return len(jsonData) + 2
+	}
+}
+
+// checkSize validates the size header of JSON data in the given mode.
+// It returns the actual size, counted in the mode or in bytes if the mode accepts several units.
+func checkSize(expected int, jsonData []byte, mode SizeMode) (int, bool) {
+	switch mode {
+	case SizeLenient:
+		return chunkSize(jsonData, SizeBytes), true
+	case SizeAuto:
+		for _, m := range []SizeMode{SizeBytes, SizeRunes, SizeUTF16} {
+			if chunkSize(jsonData, m) == expected {
+				return expected, true
+			}
+		}
+		return chunkSize(jsonData, SizeBytes), false
+	default:
+		actual := chunkSize(jsonData, mode)
+		return actual, actual == expected
+	}
+}
@@ -0,0 +1,138 @@
+package beschema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// multilingualChunk is a wrb.fr chunk with Korean text, an emoji and escaped markup.
+// Its JSON data is 105 bytes, 90 code points and 91 UTF-16 code units long.
+const multilingualChunk = `[["wrb.fr","abc123","[[\"안녕하세요 👋\",\"Grüße \\u003cb\\u003e\"]]",null,null,null,"generic"]]`
+
+func TestChunkSize(t *testing.T) {
+	// Test that each mode counts the JSON data in its unit, plus 2 for the line ending
+	testCases := []struct {
+		mode     SizeMode
+		expected int
+	}{
+		{SizeBytes, 107},
+		{SizeRunes, 92},
+		{SizeUTF16, 93},
+		{SizeAuto, 107},
+		{SizeLenient, 107},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			if size := chunkSize([]byte(multilingualChunk), tc.mode); size != tc.expected {
+				t.Errorf("Expected size %d, got %d", tc.expected, size)
+			}
+		})
+	}
+}
+
+func TestUnmarshalImplicitStreamWithSizeModes(t *testing.T) {
+	// Test that a stream counting UTF-16 code units is only accepted by matching modes
+	data := []byte(")]}'\r\n\r\n93\r\n" + multilingualChunk + "\r\n")
+
+	_, err := UnmarshalImplicitStream(data)
+	var sizeErr *SizeMismatchError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("Expected *SizeMismatchError by default, got %v", err)
+	}
+	if sizeErr.Expected != 93 || sizeErr.Actual != 107 {
+		t.Errorf("Expected size mismatch 93 != 107, got %+v", sizeErr)
+	}
+
+	testCases := []struct {
+		mode SizeMode
+		ok   bool
+	}{
+		{SizeBytes, false},
+		{SizeRunes, false},
+		{SizeUTF16, true},
+		{SizeAuto, true},
+		{SizeLenient, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			stream, err := UnmarshalImplicitStreamWithOptions(data, DecodeOptions{SizeMode: tc.mode})
+			if (err == nil) != tc.ok {
+				t.Fatalf("Expected success %v, got %v", tc.ok, err)
+			}
+			if err != nil {
+				return
+			}
+			payload, ok := stream.Schemas[0].GetString("0.2.0.0")
+			if !ok || payload != "안녕하세요 👋" {
+				t.Errorf("Expected payload '안녕하세요 👋', got %q", payload)
+			}
+		})
+	}
+}
+
+func TestDecoderWithSizeAuto(t *testing.T) {
+	// Test that auto detection accepts chunks counted in different units within one stream
+	streamData := ")]}'\r\n\r\n" +
+		"14\r\n[\"한국어\",\"😀\"]\r\n" + // UTF-16 code units
+		"13\r\n[\"한국어\",\"😀\"]\r\n" + // code points
+		"22\r\n[\"한국어\",\"😀\"]\r\n" // bytes
+
+	decoder := NewDecoder(strings.NewReader(streamData))
+	decoder.SetDecodeOptions(DecodeOptions{SizeMode: SizeAuto})
+
+	for i := 0; i < 3; i++ {
+		schema, err := decoder.Next()
+		if err != nil {
+			t.Fatalf("Next failed at chunk %d: %v", i, err)
+		}
+		if schema[1] != "😀" {
+			t.Errorf("Expected schema[1] = '😀', got %v", schema[1])
+		}
+	}
+}
+
+func TestMarshalWithSizeModes(t *testing.T) {
+	// Test that encoders write size headers in the configured unit
+	schema := ImplicitSchema{"한국어", "😀"}
+
+	data, err := MarshalImplicitSchemaWithOptions(schema, true, EncodeOptions{SizeMode: SizeUTF16})
+	if err != nil {
+		t.Fatalf("MarshalImplicitSchemaWithOptions failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "14\r\n") {
+		t.Errorf("Expected UTF-16 size 14, got %q", string(data))
+	}
+	if _, err := UnmarshalImplicitSchemaWithOptions(data, true, DecodeOptions{SizeMode: SizeUTF16}); err != nil {
+		t.Errorf("Expected round trip in UTF-16 mode, got %v", err)
+	}
+
+	data, err = MarshalExplicitSchemaWithOptions(SubEntity1{Field1: "한국어", Field2: "😀"}, EncodeOptions{SizeMode: SizeRunes})
+	if err != nil {
+		t.Fatalf("MarshalExplicitSchemaWithOptions failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "13\r\n") {
+		t.Errorf("Expected rune size 13, got %q", string(data))
+	}
+
+	var buf strings.Builder
+	encoder := NewEncoder(&buf)
+	encoder.SetMagicByte(nil)
+	encoder.SetEncodeOptions(EncodeOptions{SizeMode: SizeUTF16})
+	if err := encoder.Encode(schema); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "14\r\n") {
+		t.Errorf("Expected UTF-16 size 14, got %q", buf.String())
+	}
+
+	stream, err := MarshalImplicitStreamWithOptions(&Stream{MagicByte: []byte(DefaultMagicByte), Schemas: []ImplicitSchema{schema}}, EncodeOptions{SizeMode: SizeUTF16})
+	if err != nil {
+		t.Fatalf("MarshalImplicitStreamWithOptions failed: %v", err)
+	}
+	if !strings.Contains(string(stream), "\r\n14\r\n") {
+		t.Errorf("Expected UTF-16 size 14 in the stream, got %q", string(stream))
+	}
+}